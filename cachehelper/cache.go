@@ -0,0 +1,53 @@
+// Package cachehelper provides caches used by generated read-through cache of model
+package cachehelper
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// Cache key-value cache with expiration
+type Cache interface {
+	// Get value of key, false if not found or expired
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set value of key, expired after ttl, never expired if ttl <= 0
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Delete keys, not found keys are ignored
+	Delete(ctx context.Context, keys ...string) error
+}
+
+// Key cache key of prefix and args, args are encoded in json so that they are not ambiguous,
+// eg. user:["Email","a@b.c"]
+func Key(prefix string, args ...interface{}) string {
+	var b strings.Builder
+	b.WriteString(prefix)
+	b.WriteByte(':')
+	data, err := json.Marshal(args)
+	if err != nil {
+		// 无法编码的参数不会出现在生成代码中
+		panic(err)
+	}
+	b.Write(data)
+	return b.String()
+}
+
+// Fetch value of key from cache, otherwise load it and set to cache for ttl,
+// errors of cache are ignored so that the cache is not required for reading
+func Fetch[T any](ctx context.Context, c Cache, key string, ttl time.Duration, load func() (T, error)) (T, error) {
+	if data, ok, err := c.Get(ctx, key); err == nil && ok {
+		var v T
+		if json.Unmarshal(data, &v) == nil {
+			return v, nil
+		}
+	}
+	v, err := load()
+	if err != nil {
+		return v, err
+	}
+	if data, err := json.Marshal(v); err == nil {
+		_ = c.Set(ctx, key, data, ttl)
+	}
+	return v, nil
+}
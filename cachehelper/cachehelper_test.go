@@ -0,0 +1,251 @@
+package cachehelper
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLRU(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	c := NewLRU(2)
+	c.now = func() time.Time { return now }
+
+	c.Set(ctx, "a", []byte("1"), time.Second)
+	c.Set(ctx, "b", []byte("2"), 0)
+	if v, ok, _ := c.Get(ctx, "a"); !ok || string(v) != "1" {
+		t.Fatal("a not found")
+	}
+	// b为最久未使用
+	c.Set(ctx, "c", []byte("3"), 0)
+	if _, ok, _ := c.Get(ctx, "b"); ok {
+		t.Fatal("b is not evicted")
+	}
+	now = now.Add(time.Second)
+	if _, ok, _ := c.Get(ctx, "a"); ok {
+		t.Fatal("a is not expired")
+	}
+	c.Delete(ctx, "c", "unknown")
+	if c.Len() != 0 {
+		t.Fatal("keys are not deleted:", c.Len())
+	}
+}
+
+func TestKey(t *testing.T) {
+	if k := Key("user", "Email", "a@b.c"); k != `user:["Email","a@b.c"]` {
+		t.Fatal(k)
+	}
+	if Key("user", "NameAge", "a:1", 2) == Key("user", "NameAge", "a", "1:2") {
+		t.Fatal("ambiguous key")
+	}
+}
+
+type fetchObj struct {
+	Name string `json:"name"`
+}
+
+func TestFetch(t *testing.T) {
+	ctx := context.Background()
+	c := NewLRU(0)
+	loads := 0
+	load := func() (*fetchObj, error) {
+		loads++
+		return &fetchObj{Name: "a"}, nil
+	}
+	for i := 0; i < 2; i++ {
+		obj, err := Fetch(ctx, c, "k", time.Minute, load)
+		if err != nil || obj.Name != "a" {
+			t.Fatal(obj, err)
+		}
+	}
+	if loads != 1 {
+		t.Fatal("loaded times:", loads)
+	}
+	failed := errors.New("failed")
+	_, err := Fetch(ctx, c, "k2", time.Minute, func() (*fetchObj, error) { return nil, failed })
+	if !errors.Is(err, failed) || c.Len() != 1 {
+		t.Fatal("error is cached:", err)
+	}
+}
+
+// standIn minimal redis server of GET, SET [PX], DEL and AUTH,
+// EXEC replies an array with an error element and BLOCK never replies
+type standIn struct {
+	ln       net.Listener
+	password string
+
+	mu     sync.Mutex
+	values map[string]string
+	ttls   map[string]string
+}
+
+func newStandIn(t *testing.T, password string) *standIn {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &standIn{ln: ln, password: password, values: map[string]string{}, ttls: map[string]string{}}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	t.Cleanup(func() { ln.Close() })
+	return s
+}
+
+func (s *standIn) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	authed := s.password == ""
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		n, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+		args := make([]string, n)
+		for i := range args {
+			line, _ = r.ReadString('\n')
+			size, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+			data := make([]byte, size+2)
+			io.ReadFull(r, data)
+			args[i] = string(data[:size])
+		}
+		if args[0] == "BLOCK" {
+			continue
+		}
+		s.mu.Lock()
+		switch {
+		case args[0] == "AUTH":
+			authed = args[1] == s.password
+			fmt.Fprint(conn, "+OK\r\n")
+		case !authed:
+			fmt.Fprint(conn, "-NOAUTH Authentication required.\r\n")
+		case args[0] == "GET":
+			if v, ok := s.values[args[1]]; ok {
+				fmt.Fprintf(conn, "$%d\r\n%s\r\n", len(v), v)
+			} else {
+				fmt.Fprint(conn, "$-1\r\n")
+			}
+		case args[0] == "SET":
+			s.values[args[1]] = args[2]
+			if len(args) == 5 && args[3] == "PX" {
+				s.ttls[args[1]] = args[4]
+			}
+			fmt.Fprint(conn, "+OK\r\n")
+		case args[0] == "EXEC":
+			fmt.Fprint(conn, "*3\r\n+OK\r\n-ERR bad\r\n:1\r\n")
+		case args[0] == "DEL":
+			count := 0
+			for _, k := range args[1:] {
+				if _, ok := s.values[k]; ok {
+					delete(s.values, k)
+					count++
+				}
+			}
+			fmt.Fprintf(conn, ":%d\r\n", count)
+		default:
+			fmt.Fprintf(conn, "-ERR unknown command '%s'\r\n", args[0])
+		}
+		s.mu.Unlock()
+	}
+}
+
+func TestRedis(t *testing.T) {
+	ctx := context.Background()
+	s := newStandIn(t, "secret")
+	c := NewRedis(s.ln.Addr().String(), "secret", 2)
+	defer c.Close()
+
+	if _, ok, err := c.Get(ctx, "a"); ok || err != nil {
+		t.Fatal(ok, err)
+	}
+	err := c.Set(ctx, "a", []byte("1\r\n2"), 1500*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, ok, err := c.Get(ctx, "a"); !ok || err != nil || string(v) != "1\r\n2" {
+		t.Fatal(string(v), ok, err)
+	}
+	s.mu.Lock()
+	ttl := s.ttls["a"]
+	s.mu.Unlock()
+	if ttl != "1500" {
+		t.Fatal("ttl:", ttl)
+	}
+	err = c.Delete(ctx, "a", "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := c.Get(ctx, "a"); ok {
+		t.Fatal("a is not deleted")
+	}
+	var replyErr RedisError
+	if _, err = c.Do(ctx, "PING"); !errors.As(err, &replyErr) {
+		t.Fatal(err)
+	}
+
+	denied := NewRedis(s.ln.Addr().String(), "", 1)
+	if _, _, err = denied.Get(ctx, "a"); !errors.As(err, &replyErr) {
+		t.Fatal(err)
+	}
+}
+
+func TestRedisArrayError(t *testing.T) {
+	ctx := context.Background()
+	s := newStandIn(t, "")
+	c := NewRedis(s.ln.Addr().String(), "", 1)
+	defer c.Close()
+
+	reply, err := c.Do(ctx, "EXEC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list, ok := reply.([]interface{}); !ok || len(list) != 3 || list[0] != "OK" || list[1] != RedisError("ERR bad") || list[2] != int64(1) {
+		t.Fatalf("reply: %#v", reply)
+	}
+	// 复用的连接中没有残留的回复
+	if err = c.Set(ctx, "a", []byte("1"), 0); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok, err := c.Get(ctx, "a"); !ok || err != nil || string(v) != "1" {
+		t.Fatal(string(v), ok, err)
+	}
+}
+
+func TestRedisCancel(t *testing.T) {
+	s := newStandIn(t, "")
+	c := NewRedis(s.ln.Addr().String(), "", 1)
+	defer c.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	start := time.Now()
+	if _, err := c.Do(ctx, "BLOCK"); !errors.Is(err, context.Canceled) {
+		t.Fatal(err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Fatal("cancel does not interrupt read")
+	}
+	if _, err := c.Do(ctx, "GET", "a"); err == nil {
+		t.Fatal("canceled context accepted")
+	}
+	// 取消的连接不复用
+	if _, ok, err := c.Get(context.Background(), "a"); ok || err != nil {
+		t.Fatal(ok, err)
+	}
+}
@@ -0,0 +1,97 @@
+package cachehelper
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// LRU in-memory Cache with TTL, the least recently used key is evicted if size is exceeded
+type LRU struct {
+	mu    sync.Mutex
+	size  int
+	ll    *list.List
+	items map[string]*list.Element
+
+	now func() time.Time
+}
+
+type lruEntry struct {
+	key    string
+	value  []byte
+	expire time.Time // 零值不过期
+}
+
+// NewLRU new LRU cache of at most size keys, unlimited if size <= 0
+func NewLRU(size int) *LRU {
+	return &LRU{
+		size:  size,
+		ll:    list.New(),
+		items: make(map[string]*list.Element),
+		now:   time.Now,
+	}
+}
+
+// Get implements Cache
+func (c *LRU) Get(_ context.Context, key string) ([]byte, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.items[key]
+	if !ok {
+		return nil, false, nil
+	}
+	entry := e.Value.(*lruEntry)
+	if !entry.expire.IsZero() && !c.now().Before(entry.expire) {
+		c.remove(e)
+		return nil, false, nil
+	}
+	c.ll.MoveToFront(e)
+	return entry.value, true, nil
+}
+
+// Set implements Cache
+func (c *LRU) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var expire time.Time
+	if ttl > 0 {
+		expire = c.now().Add(ttl)
+	}
+	value = append([]byte(nil), value...)
+	if e, ok := c.items[key]; ok {
+		entry := e.Value.(*lruEntry)
+		entry.value, entry.expire = value, expire
+		c.ll.MoveToFront(e)
+		return nil
+	}
+	c.items[key] = c.ll.PushFront(&lruEntry{key: key, value: value, expire: expire})
+	if c.size > 0 && c.ll.Len() > c.size {
+		c.remove(c.ll.Back())
+	}
+	return nil
+}
+
+// Delete implements Cache
+func (c *LRU) Delete(_ context.Context, keys ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range keys {
+		if e, ok := c.items[key]; ok {
+			c.remove(e)
+		}
+	}
+	return nil
+}
+
+// Len count of keys, including expired keys not yet removed
+func (c *LRU) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+func (c *LRU) remove(e *list.Element) {
+	c.ll.Remove(e)
+	delete(c.items, e.Value.(*lruEntry).key)
+}
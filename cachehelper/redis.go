@@ -0,0 +1,236 @@
+package cachehelper
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+// Redis Cache of redis protocol (RESP2), works with redis and compatible servers
+type Redis struct {
+	addr     string
+	password string
+	idle     chan *redisConn
+	dialer   net.Dialer
+}
+
+type redisConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	w    *bufio.Writer
+}
+
+// RedisError error reply of server
+type RedisError string
+
+func (e RedisError) Error() string {
+	return "redis: " + string(e)
+}
+
+// NewRedis new redis cache of addr, eg. 127.0.0.1:6379, password is sent by AUTH if not empty,
+// at most poolSize idle connections are kept
+func NewRedis(addr, password string, poolSize int) *Redis {
+	if poolSize <= 0 {
+		poolSize = 1
+	}
+	return &Redis{
+		addr:     addr,
+		password: password,
+		idle:     make(chan *redisConn, poolSize),
+	}
+}
+
+// Get implements Cache
+func (c *Redis) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	reply, err := c.Do(ctx, "GET", key)
+	if err != nil || reply == nil {
+		return nil, false, err
+	}
+	data, ok := reply.([]byte)
+	if !ok {
+		return nil, false, fmt.Errorf("redis: unexpected reply %v of GET", reply)
+	}
+	return data, true, nil
+}
+
+// Set implements Cache
+func (c *Redis) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	args := []interface{}{"SET", key, value}
+	if ttl > 0 {
+		ms := ttl.Milliseconds()
+		if ms == 0 {
+			ms = 1
+		}
+		args = append(args, "PX", strconv.FormatInt(ms, 10))
+	}
+	_, err := c.Do(ctx, args...)
+	return err
+}
+
+// Delete implements Cache
+func (c *Redis) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	args := []interface{}{"DEL"}
+	for _, key := range keys {
+		args = append(args, key)
+	}
+	_, err := c.Do(ctx, args...)
+	return err
+}
+
+// Close close idle connections
+func (c *Redis) Close() error {
+	for {
+		select {
+		case rc := <-c.idle:
+			rc.conn.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+// Do send command, args are string or []byte, reply is nil, string, int64, []byte or []interface{},
+// error replies in array are RedisError elements
+func (c *Redis) Do(ctx context.Context, args ...interface{}) (interface{}, error) {
+	rc, err := c.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	reply, err := rc.do(ctx, args)
+	var replyErr RedisError
+	if err != nil && !errors.As(err, &replyErr) {
+		// 连接状态未知, 不复用
+		rc.conn.Close()
+		return nil, err
+	}
+	c.put(rc)
+	return reply, err
+}
+
+func (c *Redis) get(ctx context.Context) (*redisConn, error) {
+	select {
+	case rc := <-c.idle:
+		return rc, nil
+	default:
+	}
+	conn, err := c.dialer.DialContext(ctx, "tcp", c.addr)
+	if err != nil {
+		return nil, err
+	}
+	rc := &redisConn{conn: conn, r: bufio.NewReader(conn), w: bufio.NewWriter(conn)}
+	if c.password != "" {
+		_, err = rc.do(ctx, []interface{}{"AUTH", c.password})
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return rc, nil
+}
+
+func (c *Redis) put(rc *redisConn) {
+	select {
+	case c.idle <- rc:
+	default:
+		rc.conn.Close()
+	}
+}
+
+// do write command and read reply within deadline of ctx, cancel of ctx interrupts blocked write or read,
+// the connection is reusable only if err is nil or RedisError
+func (rc *redisConn) do(ctx context.Context, args []interface{}) (interface{}, error) {
+	deadline, _ := ctx.Deadline()
+	err := rc.conn.SetDeadline(deadline)
+	if err != nil {
+		return nil, err
+	}
+	// 取消时设置已过期的截止时间, 中断阻塞的读写
+	stop := context.AfterFunc(ctx, func() {
+		_ = rc.conn.SetDeadline(time.Unix(1, 0))
+	})
+	err = rc.write(args)
+	var reply interface{}
+	if err == nil {
+		reply, err = rc.read()
+	}
+	if !stop() {
+		// 截止时间已被修改, 连接不能复用
+		return nil, ctx.Err()
+	}
+	return reply, err
+}
+
+func (rc *redisConn) write(args []interface{}) error {
+	fmt.Fprintf(rc.w, "*%d\r\n", len(args))
+	for _, arg := range args {
+		var data []byte
+		switch v := arg.(type) {
+		case string:
+			data = []byte(v)
+		case []byte:
+			data = v
+		default:
+			return fmt.Errorf("redis: unsupported argument type %T", arg)
+		}
+		fmt.Fprintf(rc.w, "$%d\r\n", len(data))
+		rc.w.Write(data)
+		rc.w.WriteString("\r\n")
+	}
+	return rc.w.Flush()
+}
+
+func (rc *redisConn) read() (interface{}, error) {
+	line, err := rc.r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("redis: invalid reply %q", line)
+	}
+	kind, body := line[0], line[1:len(line)-2]
+	switch kind {
+	case '+':
+		return body, nil
+	case '-':
+		return nil, RedisError(body)
+	case ':':
+		return strconv.ParseInt(body, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(body)
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		data := make([]byte, n+2)
+		_, err = io.ReadFull(rc.r, data)
+		if err != nil {
+			return nil, err
+		}
+		return data[:n], nil
+	case '*':
+		n, err := strconv.Atoi(body)
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		// 读完所有元素, 元素的错误回复不中断读取, 否则连接中残留未读的回复
+		list := make([]interface{}, n)
+		for i := range list {
+			list[i], err = rc.read()
+			var replyErr RedisError
+			if errors.As(err, &replyErr) {
+				list[i] = replyErr
+			} else if err != nil {
+				return nil, err
+			}
+		}
+		return list, nil
+	}
+	return nil, fmt.Errorf("redis: invalid reply %q", line)
+}
@@ -0,0 +1,518 @@
+package db
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// Array postgres array of T, elements are converted by the ArrayCodec of T, see RegisterArrayCodec.
+// NULL elements are scanned as zero value, or nil of Array[*T], Array[Array[T]] is a multi-dimensional array
+type Array[T any] []T
+
+// NewArray creates Array of values
+func NewArray[T any](values ...T) Array[T] {
+	return Array[T](values)
+}
+
+// arrayDecoder Array as element of multi-dimensional array
+type arrayDecoder interface {
+	decode(items []arrayItem) error
+}
+
+// arrayEncoder Array as element of multi-dimensional array
+type arrayEncoder interface {
+	encode(b *strings.Builder) error
+	shape() ([]int, error)
+	GormDataType() string
+}
+
+// isSubArray T is Array, eg. Array[int64] of Array[Array[int64]]
+func isSubArray[T any]() bool {
+	var zero T
+	_, ok := any(&zero).(arrayDecoder)
+	return ok
+}
+
+// Scan implements the sql.Scanner interface for reading from database
+func (a *Array[T]) Scan(value interface{}) error {
+	var s string
+	switch v := value.(type) {
+	case nil:
+		*a = Array[T]{}
+		return nil
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	default:
+		return fmt.Errorf("cannot scan %T into Array[%s]", value, reflect.TypeFor[T]())
+	}
+	if s == "" {
+		*a = Array[T]{}
+		return nil
+	}
+	items, err := parseArray(s)
+	if err != nil {
+		return err
+	}
+	var result Array[T]
+	err = result.decode(items)
+	if err != nil {
+		return err
+	}
+	_, err = result.shape()
+	if err != nil {
+		return err
+	}
+	*a = result
+	return nil
+}
+
+func (a *Array[T]) decode(items []arrayItem) error {
+	result := make(Array[T], len(items))
+	if isSubArray[T]() {
+		for i, item := range items {
+			if !item.nested {
+				return fmt.Errorf("element %s of array is not an array, dimensions mismatch", item.text)
+			}
+			err := any(&result[i]).(arrayDecoder).decode(item.items)
+			if err != nil {
+				return err
+			}
+		}
+		*a = result
+		return nil
+	}
+
+	c, err := lookupCodec(reflect.TypeFor[T]())
+	if err != nil {
+		return err
+	}
+	for i, item := range items {
+		switch {
+		case item.nested:
+			return fmt.Errorf("cannot scan multi-dimensional array into Array[%s]", reflect.TypeFor[T]())
+		case item.null:
+			continue // 零值, Array[*T]为nil
+		}
+		v, err := c.parse(item.text)
+		if err != nil {
+			return fmt.Errorf("invalid element %q of Array[%s]: %w", item.text, reflect.TypeFor[T](), err)
+		}
+		result[i] = v.(T)
+	}
+	*a = result
+	return nil
+}
+
+// Value implements the driver.Valuer interface for writing to database
+func (a Array[T]) Value() (driver.Value, error) {
+	if len(a) == 0 {
+		return "{}", nil
+	}
+	_, err := a.shape()
+	if err != nil {
+		return nil, err
+	}
+	var b strings.Builder
+	err = a.encode(&b)
+	if err != nil {
+		return nil, err
+	}
+	return b.String(), nil
+}
+
+func (a Array[T]) encode(b *strings.Builder) error {
+	b.WriteByte('{')
+	if isSubArray[T]() {
+		for i, v := range a {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			err := any(v).(arrayEncoder).encode(b)
+			if err != nil {
+				return err
+			}
+		}
+		b.WriteByte('}')
+		return nil
+	}
+
+	c, err := lookupCodec(reflect.TypeFor[T]())
+	if err != nil {
+		return err
+	}
+	for i, v := range a {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		if c.nullable && reflect.ValueOf(&v).Elem().IsNil() {
+			b.WriteString("NULL")
+			continue
+		}
+		writeArrayElem(b, c.format(v))
+	}
+	b.WriteByte('}')
+	return nil
+}
+
+// shape dimensions of the array, sub-arrays of multi-dimensional array must have the same dimensions and not be empty
+func (a Array[T]) shape() ([]int, error) {
+	dims := []int{len(a)}
+	if !isSubArray[T]() {
+		return dims, nil
+	}
+	var sub []int
+	for i, v := range a {
+		s, err := any(v).(arrayEncoder).shape()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case s[0] == 0:
+			return nil, errors.New("empty sub-array of multi-dimensional array")
+		case i == 0:
+			sub = s
+		case !slices.Equal(s, sub):
+			return nil, fmt.Errorf("sub-arrays of multi-dimensional array must have matching dimensions, %v and %v", sub, s)
+		}
+	}
+	return append(dims, sub...), nil
+}
+
+// GormDataType tells GORM what data type to use, empty if no codec of T is registered
+func (Array[T]) GormDataType() string {
+	if isSubArray[T]() {
+		var zero T
+		return any(zero).(arrayEncoder).GormDataType() + "[]"
+	}
+	c, err := lookupCodec(reflect.TypeFor[T]())
+	if err != nil {
+		return ""
+	}
+	return c.dataType + "[]"
+}
+
+// String returns a string representation of the array, eg. [1, 2] or ["a", "b"]
+func (a Array[T]) String() string {
+	var b strings.Builder
+	b.WriteByte('[')
+	for i, v := range a {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(elemString(v))
+	}
+	b.WriteByte(']')
+	return b.String()
+}
+
+// elemString text of element, strings are quoted and nil is null
+func elemString[T any](v T) string {
+	if s, ok := any(v).(fmt.Stringer); ok && isSubArray[T]() {
+		return s.String()
+	}
+	rv := reflect.ValueOf(&v).Elem()
+	if rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return "null"
+		}
+		rv = rv.Elem()
+	}
+	text := fmt.Sprint(rv.Interface())
+	if c, err := lookupCodec(reflect.TypeFor[T]()); err == nil {
+		text = c.format(v)
+	}
+	if rv.Kind() == reflect.String {
+		return `"` + text + `"`
+	}
+	return text
+}
+
+// Contains checks if the array contains a specific value
+func (a Array[T]) Contains(val T) bool {
+	for _, v := range a {
+		if equal(v, val) {
+			return true
+		}
+	}
+	return false
+}
+
+// ContainsIgnoreCase checks if text of any element equals val (case insensitive)
+func (a Array[T]) ContainsIgnoreCase(val string) bool {
+	for _, v := range a {
+		if strings.EqualFold(elemText(v), val) {
+			return true
+		}
+	}
+	return false
+}
+
+// Add appends a value to the array if it doesn't already exist
+func (a *Array[T]) Add(val T) {
+	if !a.Contains(val) {
+		*a = append(*a, val)
+	}
+}
+
+// Remove removes a value from the array
+func (a *Array[T]) Remove(val T) {
+	for i, v := range *a {
+		if equal(v, val) {
+			*a = append((*a)[:i], (*a)[i+1:]...)
+			return
+		}
+	}
+}
+
+// Filter returns a new Array with elements that satisfy the predicate
+func (a Array[T]) Filter(predicate func(T) bool) Array[T] {
+	result := make(Array[T], 0)
+	for _, val := range a {
+		if predicate(val) {
+			result = append(result, val)
+		}
+	}
+	return result
+}
+
+// Join concatenates text of all elements with the given separator
+func (a Array[T]) Join(separator string) string {
+	list := make([]string, len(a))
+	for i, v := range a {
+		list[i] = elemText(v)
+	}
+	return strings.Join(list, separator)
+}
+
+// ToSlice returns a regular slice
+func (a Array[T]) ToSlice() []T {
+	return []T(a)
+}
+
+// IsEmpty returns true if the array is empty
+func (a Array[T]) IsEmpty() bool {
+	return len(a) == 0
+}
+
+// Len returns the length of the array
+func (a Array[T]) Len() int {
+	return len(a)
+}
+
+// elemText text of element by its codec, same as the element in postgres array literal without quoting
+func elemText[T any](v T) string {
+	rv := reflect.ValueOf(&v).Elem()
+	if rv.Kind() == reflect.Pointer && rv.IsNil() {
+		return "NULL"
+	}
+	if c, err := lookupCodec(reflect.TypeFor[T]()); err == nil {
+		return c.format(v)
+	}
+	return fmt.Sprint(v)
+}
+
+// equal a == b, by Equal method if exists, eg. time.Time, pointers and sub-arrays are compared by values
+func equal[T any](a, b T) bool {
+	if e, ok := any(a).(interface{ Equal(T) bool }); ok {
+		return e.Equal(b)
+	}
+	t := reflect.TypeFor[T]()
+	if t.Comparable() && t.Kind() != reflect.Pointer && t.Kind() != reflect.Interface {
+		return any(a) == any(b)
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// arrayItem element of postgres array literal
+type arrayItem struct {
+	text   string
+	null   bool
+	nested bool        // 子数组
+	items  []arrayItem // 子数组的元素
+}
+
+var errArraySyntax = errors.New("invalid array literal")
+
+// parseArray parse postgres array literal, eg. {1,NULL}, {{"a b","c\"d"},{e,f}} or [0:1]={1,2}
+func parseArray(s string) ([]arrayItem, error) {
+	s = strings.TrimSpace(s)
+	// 忽略维度信息, 如[0:1]={1,2}
+	if strings.HasPrefix(s, "[") {
+		_, after, ok := strings.Cut(s, "=")
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", errArraySyntax, s)
+		}
+		s = strings.TrimSpace(after)
+	}
+	p := &arrayParser{s: s}
+	if p.peek() != '{' {
+		return nil, fmt.Errorf("%w: %s", errArraySyntax, s)
+	}
+	items, err := p.parse()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %s", errArraySyntax, s, err)
+	}
+	p.skipSpace()
+	if p.pos < len(p.s) {
+		return nil, fmt.Errorf("%w: %s: unexpected %q at %d", errArraySyntax, s, p.s[p.pos:], p.pos)
+	}
+	return items, nil
+}
+
+type arrayParser struct {
+	s   string
+	pos int
+}
+
+func (p *arrayParser) peek() byte {
+	if p.pos >= len(p.s) {
+		return 0
+	}
+	return p.s[p.pos]
+}
+
+func (p *arrayParser) skipSpace() {
+	for p.pos < len(p.s) && isArraySpace(p.s[p.pos]) {
+		p.pos++
+	}
+}
+
+// parse elements between braces, p.pos is at '{'
+func (p *arrayParser) parse() ([]arrayItem, error) {
+	p.pos++
+	items := make([]arrayItem, 0)
+	p.skipSpace()
+	if p.peek() == '}' {
+		p.pos++
+		return items, nil
+	}
+	for {
+		p.skipSpace()
+		var item arrayItem
+		switch p.peek() {
+		case '{':
+			sub, err := p.parse()
+			if err != nil {
+				return nil, err
+			}
+			item = arrayItem{nested: true, items: sub}
+		case '"':
+			text, err := p.quoted()
+			if err != nil {
+				return nil, err
+			}
+			item.text = text
+		default:
+			text, escaped, err := p.unquoted()
+			if err != nil {
+				return nil, err
+			}
+			item.text, item.null = text, !escaped && strings.EqualFold(text, "NULL")
+		}
+		items = append(items, item)
+
+		p.skipSpace()
+		switch c := p.peek(); c {
+		case ',':
+			p.pos++
+		case '}':
+			p.pos++
+			return items, nil
+		case 0:
+			return nil, errors.New("unexpected end")
+		default:
+			return nil, fmt.Errorf("unexpected %q at %d", c, p.pos)
+		}
+	}
+}
+
+// quoted element, backslash escapes any character, p.pos is at '"'
+func (p *arrayParser) quoted() (string, error) {
+	var b strings.Builder
+	for p.pos++; p.pos < len(p.s); p.pos++ {
+		switch c := p.s[p.pos]; c {
+		case '\\':
+			p.pos++
+			if p.pos >= len(p.s) {
+				return "", errors.New("unexpected end")
+			}
+			b.WriteByte(p.s[p.pos])
+		case '"':
+			p.pos++
+			return b.String(), nil
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", errors.New("unterminated quoted element")
+}
+
+// unquoted element ends with ',' or '}', spaces around it are ignored unless escaped,
+// escaped is true if any character is escaped, eg. \NULL is not NULL
+func (p *arrayParser) unquoted() (text string, escaped bool, err error) {
+	var b strings.Builder
+	keep := 0 // 转义的字符不会被去掉
+	for ; p.pos < len(p.s); p.pos++ {
+		switch c := p.s[p.pos]; c {
+		case ',', '}':
+			text = b.String()[:keep] + strings.TrimRightFunc(b.String()[keep:], func(r rune) bool {
+				return r < 0x80 && isArraySpace(byte(r))
+			})
+			if text == "" {
+				return "", false, fmt.Errorf("empty element at %d", p.pos)
+			}
+			return text, keep > 0, nil
+		case '{', '"':
+			return "", false, fmt.Errorf("unexpected %q at %d", c, p.pos)
+		case '\\':
+			p.pos++
+			if p.pos >= len(p.s) {
+				return "", false, errors.New("unexpected end")
+			}
+			b.WriteByte(p.s[p.pos])
+			keep = b.Len()
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", false, errors.New("unexpected end")
+}
+
+func isArraySpace(c byte) bool {
+	switch c {
+	case ' ', '\t', '\n', '\r', '\v', '\f':
+		return true
+	}
+	return false
+}
+
+// writeArrayElem write element of array literal, quoted if necessary
+func writeArrayElem(b *strings.Builder, s string) {
+	quote := s == "" || strings.EqualFold(s, "NULL")
+	for i := 0; i < len(s) && !quote; i++ {
+		switch s[i] {
+		case '{', '}', ',', '"', '\\':
+			quote = true
+		default:
+			quote = isArraySpace(s[i])
+		}
+	}
+	if !quote {
+		b.WriteString(s)
+		return
+	}
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		if s[i] == '"' || s[i] == '\\' {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(s[i])
+	}
+	b.WriteByte('"')
+}
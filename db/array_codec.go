@@ -0,0 +1,197 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ArrayCodec converts element of Array between T and its text in postgres array literal
+type ArrayCodec[T any] interface {
+	// DataType postgres type of element, eg. integer
+	DataType() string
+	// Parse element text, quotes and escapes are removed
+	Parse(s string) (T, error)
+	// Format element text, it is quoted and escaped if necessary
+	Format(v T) string
+}
+
+// ArrayCodecFunc ArrayCodec by functions
+type ArrayCodecFunc[T any] struct {
+	Type       string
+	ParseFunc  func(s string) (T, error)
+	FormatFunc func(v T) string
+}
+
+// DataType implements ArrayCodec
+func (c ArrayCodecFunc[T]) DataType() string { return c.Type }
+
+// Parse implements ArrayCodec
+func (c ArrayCodecFunc[T]) Parse(s string) (T, error) { return c.ParseFunc(s) }
+
+// Format implements ArrayCodec
+func (c ArrayCodecFunc[T]) Format(v T) string { return c.FormatFunc(v) }
+
+// Numeric exact text of postgres numeric, eg. Array[Numeric] for numeric[]
+type Numeric string
+
+// Rat value of n, false if n is NaN or Infinity
+func (n Numeric) Rat() (*big.Rat, bool) {
+	return new(big.Rat).SetString(string(n))
+}
+
+// regexpNumeric text of numeric, big.Rat accepts more forms, eg. 1/2 and hex floats
+var regexpNumeric = regexp.MustCompile(`^([+-]?(\d+\.?\d*|\.\d+)([eE][+-]?\d+)?|NaN|[+-]?Infinity)$`)
+
+// elemCodec ArrayCodec of any type, nullable if the type is pointer
+type elemCodec struct {
+	dataType string
+	nullable bool
+	parse    func(s string) (any, error)
+	format   func(v any) string
+}
+
+var (
+	codecMu sync.RWMutex
+	codecs  = make(map[reflect.Type]*elemCodec)
+)
+
+// RegisterArrayCodec register codec of Array[T] and Array[*T], the codec of T is replaced if exists
+func RegisterArrayCodec[T any](codec ArrayCodec[T]) {
+	codecMu.Lock()
+	defer codecMu.Unlock()
+	codecs[reflect.TypeFor[T]()] = &elemCodec{
+		dataType: codec.DataType(),
+		parse: func(s string) (any, error) {
+			return codec.Parse(s)
+		},
+		format: func(v any) string {
+			return codec.Format(v.(T))
+		},
+	}
+}
+
+// lookupCodec codec of t, codec of *T is derived from T
+func lookupCodec(t reflect.Type) (*elemCodec, error) {
+	codecMu.RLock()
+	c, ok := codecs[t]
+	if !ok && t.Kind() == reflect.Pointer {
+		c, ok = codecs[t.Elem()]
+	}
+	codecMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("no array codec of %s, see RegisterArrayCodec", t)
+	}
+	if t.Kind() != reflect.Pointer {
+		return c, nil
+	}
+	return &elemCodec{
+		dataType: c.dataType,
+		nullable: true,
+		parse: func(s string) (any, error) {
+			v, err := c.parse(s)
+			if err != nil {
+				return nil, err
+			}
+			p := reflect.New(t.Elem())
+			p.Elem().Set(reflect.ValueOf(v))
+			return p.Interface(), nil
+		},
+		format: func(v any) string {
+			return c.format(reflect.ValueOf(v).Elem().Interface())
+		},
+	}, nil
+}
+
+// intCodec codec of signed integer
+func intCodec[T int | int16 | int32 | int64](dataType string, bits int) ArrayCodec[T] {
+	return ArrayCodecFunc[T]{
+		Type: dataType,
+		ParseFunc: func(s string) (T, error) {
+			v, err := strconv.ParseInt(s, 10, bits)
+			return T(v), err
+		},
+		FormatFunc: func(v T) string {
+			return strconv.FormatInt(int64(v), 10)
+		},
+	}
+}
+
+// floatCodec codec of float, NaN and Infinity are supported
+func floatCodec[T float32 | float64](dataType string, bits int) ArrayCodec[T] {
+	return ArrayCodecFunc[T]{
+		Type: dataType,
+		ParseFunc: func(s string) (T, error) {
+			v, err := strconv.ParseFloat(s, bits)
+			return T(v), err
+		},
+		FormatFunc: func(v T) string {
+			return strconv.FormatFloat(float64(v), 'g', -1, bits)
+		},
+	}
+}
+
+// timeLayouts output of timestamptz, timestamp and date
+var timeLayouts = []string{
+	"2006-01-02 15:04:05Z07",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05Z07:00:00",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	time.RFC3339Nano,
+}
+
+func parseTime(s string) (time.Time, error) {
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, errors.New("unsupported time format")
+}
+
+func init() {
+	RegisterArrayCodec[string](ArrayCodecFunc[string]{
+		Type:       "text",
+		ParseFunc:  func(s string) (string, error) { return s, nil },
+		FormatFunc: func(v string) string { return v },
+	})
+	RegisterArrayCodec(intCodec[int64]("integer", 64)) // 兼容Int64Array
+	RegisterArrayCodec(intCodec[int32]("integer", 32))
+	RegisterArrayCodec(intCodec[int16]("smallint", 16))
+	RegisterArrayCodec(intCodec[int]("bigint", 64))
+	RegisterArrayCodec(floatCodec[float64]("double precision", 64))
+	RegisterArrayCodec(floatCodec[float32]("real", 32))
+	RegisterArrayCodec[bool](ArrayCodecFunc[bool]{
+		Type:       "boolean",
+		ParseFunc:  strconv.ParseBool, // t/f
+		FormatFunc: strconv.FormatBool,
+	})
+	RegisterArrayCodec[time.Time](ArrayCodecFunc[time.Time]{
+		Type:       "timestamptz",
+		ParseFunc:  parseTime,
+		FormatFunc: func(v time.Time) string { return v.Format(time.RFC3339Nano) },
+	})
+	RegisterArrayCodec[uuid.UUID](ArrayCodecFunc[uuid.UUID]{
+		Type:       "uuid",
+		ParseFunc:  uuid.Parse,
+		FormatFunc: uuid.UUID.String,
+	})
+	RegisterArrayCodec[Numeric](ArrayCodecFunc[Numeric]{
+		Type: "numeric",
+		ParseFunc: func(s string) (Numeric, error) {
+			if !regexpNumeric.MatchString(s) {
+				return "", errors.New("invalid numeric")
+			}
+			return Numeric(s), nil
+		},
+		FormatFunc: func(v Numeric) string { return string(v) },
+	})
+}
@@ -0,0 +1,282 @@
+package db
+
+import (
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestArrayScanNull(t *testing.T) {
+	var s StringArray
+	if err := s.Scan("{a,NULL}"); err != nil || !reflect.DeepEqual(s, StringArray{"a", ""}) {
+		t.Fatalf("StringArray: %v %#v", err, s)
+	}
+	var i Int64Array
+	if err := i.Scan("{1,NULL,3}"); err != nil || !reflect.DeepEqual(i, Int64Array{1, 0, 3}) {
+		t.Fatalf("Int64Array: %v %#v", err, i)
+	}
+	var p Array[*string]
+	if err := p.Scan(`{a,NULL,"NULL"}`); err != nil || len(p) != 3 || p[1] != nil || *p[2] != "NULL" {
+		t.Fatalf("Array[*string]: %v %v", err, p)
+	}
+}
+
+func TestArrayShape(t *testing.T) {
+	var a Array[Array[int64]]
+	for _, in := range []string{`{{1},{2,3}}`, `{{},{}}`, `{{1,2},{}}`} {
+		if err := a.Scan(in); err == nil {
+			t.Fatalf("%s accepted: %v", in, a)
+		}
+	}
+	var b Array[Array[Array[int64]]]
+	if err := b.Scan(`{{{1},{2}},{{3,4},{5,6}}}`); err == nil {
+		t.Fatalf("accepted: %v", b)
+	}
+	if err := b.Scan(`{{{1,2}},{{3,4}}}`); err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []Array[Array[int64]]{{{1}, {2, 3}}, {{}, {}}} {
+		if _, err := v.Value(); err == nil {
+			t.Fatalf("%v accepted", v)
+		}
+	}
+	if v, err := (Array[Array[int64]]{}).Value(); err != nil || v != "{}" {
+		t.Fatal(v, err)
+	}
+}
+
+func TestArrayNumeric(t *testing.T) {
+	var n Array[Numeric]
+	if err := n.Scan(`{1,-1.50,.5,2.,1e-3,+1E+10,NaN,Infinity,-Infinity}`); err != nil || len(n) != 9 || n[1] != "-1.50" {
+		t.Fatal(err, n)
+	}
+	for _, in := range []string{`{1/2}`, `{0x1p-2}`, `{1_000}`, `{.}`, `{e5}`, `{nan}`} {
+		if err := n.Scan(in); err == nil {
+			t.Fatalf("%s accepted: %v", in, n)
+		}
+	}
+}
+
+func TestParseArray(t *testing.T) {
+	text := func(list ...string) []arrayItem {
+		items := make([]arrayItem, len(list))
+		for i, v := range list {
+			items[i] = arrayItem{text: v}
+		}
+		return items
+	}
+	null := arrayItem{text: "NULL", null: true}
+	cases := []struct {
+		in    string
+		items []arrayItem
+	}{
+		{`{}`, []arrayItem{}},
+		{` { } `, []arrayItem{}},
+		{`{a,b}`, text("a", "b")},
+		{`{ a b , c }`, text("a b", "c")},
+		{`{"a,b","c\"d","e\\f","","{}"," x "}`, text("a,b", `c"d`, `e\f`, "", "{}", " x ")},
+		{`{a\,b,c\ ,\ d}`, text("a,b", "c ", " d")},
+		{`{NULL,null,"NULL",\NULL}`, []arrayItem{null, {text: "null", null: true}, {text: "NULL"}, {text: "NULL"}}},
+		{`{"中文",é}`, text("中文", "é")},
+		{`{{1,2},{3,NULL}}`, []arrayItem{{nested: true, items: text("1", "2")}, {nested: true, items: []arrayItem{{text: "3"}, null}}}},
+		{`{{{a}},{{b}}}`, []arrayItem{
+			{nested: true, items: []arrayItem{{nested: true, items: text("a")}}},
+			{nested: true, items: []arrayItem{{nested: true, items: text("b")}}},
+		}},
+		{`[0:1]={1,2}`, text("1", "2")},
+		{`[1:2][1:1]={{a},{b}}`, []arrayItem{{nested: true, items: text("a")}, {nested: true, items: text("b")}}},
+	}
+	for _, c := range cases {
+		items, err := parseArray(c.in)
+		if err != nil {
+			t.Fatalf("%s: %v", c.in, err)
+		}
+		if !reflect.DeepEqual(items, c.items) {
+			t.Fatalf("%s: %#v", c.in, items)
+		}
+	}
+
+	for _, in := range []string{``, `a`, `{a`, `{a,}`, `{,a}`, `{a,,b}`, `{"a}`, `{"a"b}`, `{a"b"}`, `{a{b}}`, `{a} x`, `{a\`, `[0:1]{1}`, `[0:1]=1`} {
+		if items, err := parseArray(in); err == nil {
+			t.Fatalf("%s accepted: %#v", in, items)
+		}
+	}
+}
+
+func TestWriteArrayElem(t *testing.T) {
+	for in, want := range map[string]string{
+		"abc":  "abc",
+		"":     `""`,
+		"NULL": `"NULL"`,
+		"null": `"null"`,
+		"a b":  `"a b"`,
+		"a\tb": "\"a\tb\"",
+		"a,b":  `"a,b"`,
+		"{a}":  `"{a}"`,
+		`a"b`:  `"a\"b"`,
+		`a\b`:  `"a\\b"`,
+		"中文":   "中文",
+	} {
+		var b strings.Builder
+		writeArrayElem(&b, in)
+		if b.String() != want {
+			t.Fatalf("%q: %s", in, b.String())
+		}
+	}
+}
+
+// roundTrip Value then Scan of a, the result must be equal to a
+func roundTrip[T any](t *testing.T, a Array[T], literal string) {
+	t.Helper()
+	v, err := a.Value()
+	if err != nil {
+		t.Fatalf("%v: %v", a, err)
+	}
+	if literal != "" && v != literal {
+		t.Fatalf("%v: %s, want %s", a, v, literal)
+	}
+	var b Array[T]
+	err = b.Scan([]byte(v.(string)))
+	if err != nil {
+		t.Fatalf("%s: %v", v, err)
+	}
+	if len(a) != len(b) {
+		t.Fatalf("%s: %v", v, b)
+	}
+	for i := range a {
+		if !equal(a[i], b[i]) {
+			t.Fatalf("%s: %v", v, b)
+		}
+	}
+}
+
+func TestArrayRoundTrip(t *testing.T) {
+	s1, s2 := "a", "NULL"
+	roundTrip(t, StringArray{"plain", "", "NULL", "a,b", `c"d`, `e\f`, " x ", "{}", "中文"},
+		`{plain,"","NULL","a,b","c\"d","e\\f"," x ","{}",中文}`)
+	roundTrip(t, Array[*string]{&s1, nil, &s2}, `{a,NULL,"NULL"}`)
+	roundTrip(t, Int64Array{math.MinInt64, 0, math.MaxInt64}, "{-9223372036854775808,0,9223372036854775807}")
+	roundTrip(t, Array[int32]{math.MinInt32, math.MaxInt32}, "{-2147483648,2147483647}")
+	roundTrip(t, Array[int16]{-1, math.MaxInt16}, "{-1,32767}")
+	roundTrip(t, Array[int]{1, -2}, "{1,-2}")
+	roundTrip(t, Array[float64]{1.5, -0.1, 1e300, math.Inf(1), math.Inf(-1)}, "{1.5,-0.1,1e+300,+Inf,-Inf}")
+	roundTrip(t, Array[float32]{1.5, -0.1}, "{1.5,-0.1}")
+	roundTrip(t, Array[bool]{true, false}, "{true,false}")
+	roundTrip(t, Array[time.Time]{
+		time.Date(2024, 1, 2, 3, 4, 5, 123456000, time.UTC),
+		time.Date(2024, 1, 2, 3, 4, 5, 0, time.FixedZone("", 8*3600)),
+	}, "{2024-01-02T03:04:05.123456Z,2024-01-02T03:04:05+08:00}")
+	id := uuid.MustParse("6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+	roundTrip(t, Array[uuid.UUID]{id, uuid.Nil}, "{6ba7b810-9dad-11d1-80b4-00c04fd430c8,00000000-0000-0000-0000-000000000000}")
+	roundTrip(t, Array[Numeric]{"1.50", "-0.001", "1e10", "NaN"}, "{1.50,-0.001,1e10,NaN}")
+	roundTrip(t, Array[Array[int64]]{{1, 2}, {3, 4}}, "{{1,2},{3,4}}")
+	roundTrip(t, Array[Array[string]]{{"a b", ""}, {"NULL", "c"}}, `{{"a b",""},{"NULL",c}}`)
+	roundTrip(t, Array[Array[Array[bool]]]{{{true}, {false}}}, "{{{true},{false}}}")
+	roundTrip(t, Array[int64]{}, "{}")
+}
+
+func TestArrayScanPostgres(t *testing.T) {
+	// postgres输出的格式
+	var ts Array[time.Time]
+	err := ts.Scan(`{"2024-01-02 03:04:05.123+08","2024-01-02 03:04:05+05:30","2024-01-02 03:04:05","2024-01-02"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := time.Date(2024, 1, 2, 3, 4, 5, 123000000, time.FixedZone("", 8*3600))
+	if !ts[0].Equal(want) || ts[1].Format(time.RFC3339) != "2024-01-02T03:04:05+05:30" || !ts[3].Equal(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Fatal(ts)
+	}
+	var b Array[bool]
+	if err = b.Scan("{t,f}"); err != nil || !reflect.DeepEqual(b, Array[bool]{true, false}) {
+		t.Fatal(err, b)
+	}
+	var f Array[float64]
+	if err = f.Scan("{NaN,Infinity,-Infinity}"); err != nil || !math.IsNaN(f[0]) || !math.IsInf(f[1], 1) || !math.IsInf(f[2], -1) {
+		t.Fatal(err, f)
+	}
+
+	for _, c := range []struct {
+		a  interface{ Scan(any) error }
+		in any
+	}{
+		{new(Int64Array), "{1.5}"},
+		{new(Int64Array), "{a}"},
+		{new(Array[int16]), "{40000}"},
+		{new(Array[bool]), "{yes}"},
+		{new(Array[uuid.UUID]), "{x}"},
+		{new(Array[time.Time]), "{x}"},
+		{new(Int64Array), "{{1}}"},
+		{new(Array[Array[int64]]), "{1}"},
+		{new(Int64Array), 1},
+		{new(Array[struct{}]), "{a}"},
+	} {
+		if err = c.a.Scan(c.in); err == nil {
+			t.Fatalf("%v accepted by %T", c.in, c.a)
+		}
+	}
+}
+
+type point struct{ x, y int }
+
+func TestRegisterArrayCodec(t *testing.T) {
+	var a Array[point]
+	if _, err := (Array[point]{{}}).Value(); err == nil || a.GormDataType() != "" {
+		t.Fatal("no codec of point")
+	}
+	RegisterArrayCodec[point](ArrayCodecFunc[point]{
+		Type: "point",
+		ParseFunc: func(s string) (point, error) {
+			x, y, _ := strings.Cut(strings.Trim(s, "()"), ",")
+			px, err := strconv.Atoi(x)
+			if err != nil {
+				return point{}, err
+			}
+			py, err := strconv.Atoi(y)
+			return point{px, py}, err
+		},
+		FormatFunc: func(v point) string { return "(" + strconv.Itoa(v.x) + "," + strconv.Itoa(v.y) + ")" },
+	})
+	roundTrip(t, Array[point]{{1, 2}, {-3, 4}}, `{"(1,2)","(-3,4)"}`)
+	roundTrip(t, Array[*point]{{1, 2}, nil}, `{"(1,2)",NULL}`)
+	if a.GormDataType() != "point[]" || (Array[Array[*point]]{}).GormDataType() != "point[][]" {
+		t.Fatal(a.GormDataType())
+	}
+}
+
+func TestArrayMethods(t *testing.T) {
+	if Int64Array(nil).GormDataType() != "integer[]" || StringArray(nil).GormDataType() != "text[]" {
+		t.Fatal("GormDataType of aliases")
+	}
+	i := NewInt64ArrayWithValue(1, 2)
+	i.Add(2)
+	i.Add(3)
+	i.Remove(1)
+	if !reflect.DeepEqual(i, Int64Array{2, 3}) || i.String() != "[2, 3]" || !i.Contains(3) || i.Contains(1) {
+		t.Fatal(i)
+	}
+	s := FromStringSlice([]string{"a", "B"})
+	if s.String() != `["a", "B"]` || s.Join("|") != "a|B" || !s.ContainsIgnoreCase("b") || s.Filter(func(v string) bool { return v == "a" }).Len() != 1 {
+		t.Fatal(s)
+	}
+	if v, err := StringArray(nil).Value(); err != nil || v != "{}" || !StringArray(nil).IsEmpty() {
+		t.Fatal(v, err)
+	}
+	s1, s2 := "a", "a"
+	p := Array[*string]{&s1, nil}
+	if !p.Contains(&s2) || p.String() != `["a", null]` {
+		t.Fatal(p)
+	}
+	ts := Array[time.Time]{time.Date(2024, 1, 2, 3, 4, 5, 0, time.FixedZone("", 8*3600))}
+	if !ts.Contains(ts[0].UTC()) {
+		t.Fatal("Equal of time")
+	}
+	m := Array[Array[int64]]{{1, 2}, {3, 4}}
+	if !m.Contains(Array[int64]{3, 4}) || m.String() != "[[1, 2], [3, 4]]" || m.GormDataType() != "integer[][]" {
+		t.Fatal(m)
+	}
+}
@@ -0,0 +1,83 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// actions of change history
+const (
+	HistoryUpdate = "update"
+	HistoryDelete = "delete"
+)
+
+type actorKey struct{}
+
+// WithActor context with actor, used by generated dao to fill created_by/updated_by and history
+func WithActor[T comparable](ctx context.Context, actor T) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFrom actor of context, false if not found, type mismatch or zero value
+func ActorFrom[T comparable](ctx context.Context) (T, bool) {
+	var zero T
+	actor, ok := ctx.Value(actorKey{}).(T)
+	return actor, ok && actor != zero
+}
+
+// ActorString actor of context in string, empty if not found
+func ActorString(ctx context.Context) string {
+	actor := ctx.Value(actorKey{})
+	if actor == nil {
+		return ""
+	}
+	return fmt.Sprint(actor)
+}
+
+// JSONDiff fields of before and after which are different in json, both are nil if no difference
+func JSONDiff(before, after interface{}) (json.RawMessage, json.RawMessage, error) {
+	a, err := jsonFields(before)
+	if err != nil {
+		return nil, nil, err
+	}
+	b, err := jsonFields(after)
+	if err != nil {
+		return nil, nil, err
+	}
+	da := make(map[string]json.RawMessage)
+	db := make(map[string]json.RawMessage)
+	for k, v := range a {
+		if vv, ok := b[k]; !ok || !bytes.Equal(v, vv) {
+			da[k] = v
+		}
+	}
+	for k, v := range b {
+		if vv, ok := a[k]; !ok || !bytes.Equal(v, vv) {
+			db[k] = v
+		}
+	}
+	if len(da) == 0 && len(db) == 0 {
+		return nil, nil, nil
+	}
+	da2, err := json.Marshal(da)
+	if err != nil {
+		return nil, nil, err
+	}
+	db2, err := json.Marshal(db)
+	if err != nil {
+		return nil, nil, err
+	}
+	return da2, db2, nil
+}
+
+func jsonFields(v interface{}) (map[string]json.RawMessage, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	fields := make(map[string]json.RawMessage)
+	err = json.Unmarshal(data, &fields)
+	return fields, err
+}
@@ -0,0 +1,363 @@
+package db
+
+import (
+	"cmp"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// operators of Cond
+const (
+	OpEq      = "="
+	OpNe      = "<>"
+	OpGt      = ">"
+	OpGte     = ">="
+	OpLt      = "<"
+	OpLte     = "<="
+	OpIn      = "IN"
+	OpNotIn   = "NOT IN"
+	OpIsNull  = "IS NULL"
+	OpNotNull = "IS NOT NULL"
+)
+
+var mongoOps = map[string]string{
+	OpEq:    "$eq",
+	OpNe:    "$ne",
+	OpGt:    "$gt",
+	OpGte:   "$gte",
+	OpLt:    "$lt",
+	OpLte:   "$lte",
+	OpIn:    "$in",
+	OpNotIn: "$nin",
+}
+
+// Column typed column of generated table, eg. Users.Email, name is the column of postgres or field of mongodb
+type Column[T any] struct {
+	Name string
+}
+
+// Eq column = v
+func (c Column[T]) Eq(v T) Cond { return Cond{column: c.Name, op: OpEq, value: v} }
+
+// Ne column <> v
+func (c Column[T]) Ne(v T) Cond { return Cond{column: c.Name, op: OpNe, value: v} }
+
+// Gt column > v
+func (c Column[T]) Gt(v T) Cond { return Cond{column: c.Name, op: OpGt, value: v} }
+
+// Gte column >= v
+func (c Column[T]) Gte(v T) Cond { return Cond{column: c.Name, op: OpGte, value: v} }
+
+// Lt column < v
+func (c Column[T]) Lt(v T) Cond { return Cond{column: c.Name, op: OpLt, value: v} }
+
+// Lte column <= v
+func (c Column[T]) Lte(v T) Cond { return Cond{column: c.Name, op: OpLte, value: v} }
+
+// In column IN (values)
+func (c Column[T]) In(values ...T) Cond { return Cond{column: c.Name, op: OpIn, value: values} }
+
+// NotIn column NOT IN (values)
+func (c Column[T]) NotIn(values ...T) Cond { return Cond{column: c.Name, op: OpNotIn, value: values} }
+
+// IsNull column IS NULL
+func (c Column[T]) IsNull() Cond { return Cond{column: c.Name, op: OpIsNull} }
+
+// NotNull column IS NOT NULL
+func (c Column[T]) NotNull() Cond { return Cond{column: c.Name, op: OpNotNull} }
+
+// Asc order by column ascending
+func (c Column[T]) Asc() Order { return Order{Column: c.Name} }
+
+// Desc order by column descending
+func (c Column[T]) Desc() Order { return Order{Column: c.Name, Desc: true} }
+
+// Set assignment of column for Fields
+func (c Column[T]) Set(v T) Assignment { return Assignment{column: c.Name, value: v} }
+
+// Assignment typed value of column, created by Column.Set
+type Assignment struct {
+	column string
+	value  interface{}
+}
+
+// Fields update fields of postgres Update<Table> by assignments, eg. Fields(Users.Name.Set("a"))
+func Fields(assignments ...Assignment) map[string]interface{} {
+	fields := make(map[string]interface{}, len(assignments))
+	for _, a := range assignments {
+		fields[a.column] = a.value
+	}
+	return fields
+}
+
+// Cond condition of query, created by Column or Or
+type Cond struct {
+	column string
+	op     string
+	value  interface{}
+	isOr   bool
+	or     []Cond
+}
+
+// Or any of conds, Or without cond matches nothing
+func Or(conds ...Cond) Cond {
+	return Cond{isOr: true, or: conds}
+}
+
+// Order order of query, created by Column.Asc or Column.Desc
+type Order struct {
+	Column string
+	Desc   bool
+}
+
+// Query typed query of table T, conditions are combined with AND, eg.
+// Users.Query().Where(Users.Email.Eq(x)).OrderBy(Users.CreatedAt.Desc()).Limit(n)
+type Query[T any] struct {
+	conds  []Cond
+	orders []Order
+	offset int
+	limit  int
+}
+
+// Where add conditions
+func (q *Query[T]) Where(conds ...Cond) *Query[T] {
+	q.conds = append(q.conds, conds...)
+	return q
+}
+
+// OrderBy add orders
+func (q *Query[T]) OrderBy(orders ...Order) *Query[T] {
+	q.orders = append(q.orders, orders...)
+	return q
+}
+
+// Offset skip n objects
+func (q *Query[T]) Offset(n int) *Query[T] {
+	q.offset = n
+	return q
+}
+
+// Limit at most n objects, unlimited if n <= 0
+func (q *Query[T]) Limit(n int) *Query[T] {
+	q.limit = n
+	return q
+}
+
+// Orders orders of query
+func (q *Query[T]) Orders() []Order {
+	return q.orders
+}
+
+// Page offset and limit of query, zero if not set
+func (q *Query[T]) Page() (offset, limit int) {
+	return q.offset, q.limit
+}
+
+// SQL where clause and args of conditions, empty if no condition
+func (q *Query[T]) SQL() (string, []interface{}) {
+	var args []interface{}
+	list := make([]string, 0, len(q.conds))
+	for _, c := range q.conds {
+		list = append(list, c.sql(&args))
+	}
+	return strings.Join(list, " AND "), args
+}
+
+func (c Cond) sql(args *[]interface{}) string {
+	if c.isOr && len(c.or) == 0 {
+		return "FALSE"
+	}
+	if c.isOr {
+		list := make([]string, 0, len(c.or))
+		for _, v := range c.or {
+			list = append(list, v.sql(args))
+		}
+		return "(" + strings.Join(list, " OR ") + ")"
+	}
+	column := `"` + c.column + `"`
+	switch c.op {
+	case OpIsNull, OpNotNull:
+		return column + " " + c.op
+	case OpIn, OpNotIn:
+		*args = append(*args, c.value)
+		return column + " " + c.op + " (?)"
+	}
+	*args = append(*args, c.value)
+	return column + " " + c.op + " ?"
+}
+
+// OrderSQL order clause of query, empty if no order
+func (q *Query[T]) OrderSQL() string {
+	list := make([]string, 0, len(q.orders))
+	for _, o := range q.orders {
+		if o.Desc {
+			list = append(list, `"`+o.Column+`" DESC`)
+		} else {
+			list = append(list, `"`+o.Column+`"`)
+		}
+	}
+	return strings.Join(list, ", ")
+}
+
+// Filter mongodb filter of conditions
+func (q *Query[T]) Filter() map[string]interface{} {
+	switch len(q.conds) {
+	case 0:
+		return map[string]interface{}{}
+	case 1:
+		return q.conds[0].filter()
+	}
+	list := make([]interface{}, 0, len(q.conds))
+	for _, c := range q.conds {
+		list = append(list, c.filter())
+	}
+	return map[string]interface{}{"$and": list}
+}
+
+func (c Cond) filter() map[string]interface{} {
+	if c.isOr && len(c.or) == 0 {
+		// $or不能为空数组
+		return map[string]interface{}{"_id": map[string]interface{}{"$in": []interface{}{}}}
+	}
+	if c.isOr {
+		list := make([]interface{}, 0, len(c.or))
+		for _, v := range c.or {
+			list = append(list, v.filter())
+		}
+		return map[string]interface{}{"$or": list}
+	}
+	switch c.op {
+	case OpIsNull:
+		return map[string]interface{}{c.column: nil}
+	case OpNotNull:
+		return map[string]interface{}{c.column: map[string]interface{}{"$ne": nil}}
+	}
+	return map[string]interface{}{c.column: map[string]interface{}{mongoOps[c.op]: c.value}}
+}
+
+// Match whether obj matches conditions, value returns value of column of obj, zero value is null,
+// used by in-memory fakes
+func (q *Query[T]) Match(obj *T, value func(obj *T, column string) interface{}) bool {
+	for _, c := range q.conds {
+		if !c.match(func(column string) interface{} { return value(obj, column) }) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c Cond) match(value func(column string) interface{}) bool {
+	if c.isOr {
+		for _, v := range c.or {
+			if v.match(value) {
+				return true
+			}
+		}
+		return false
+	}
+	v := value(c.column)
+	switch c.op {
+	case OpIsNull, OpNotNull:
+		null := v == nil || reflect.ValueOf(v).IsZero()
+		return null == (c.op == OpIsNull)
+	case OpIn, OpNotIn:
+		in := false
+		values := reflect.ValueOf(c.value)
+		for i := 0; i < values.Len() && !in; i++ {
+			in = compare(v, values.Index(i).Interface()) == 0
+		}
+		return in == (c.op == OpIn)
+	case OpEq:
+		return compare(v, c.value) == 0
+	case OpNe:
+		return compare(v, c.value) != 0
+	}
+	n, ok := order(v, c.value)
+	if !ok {
+		return false
+	}
+	switch c.op {
+	case OpGt:
+		return n > 0
+	case OpGte:
+		return n >= 0
+	case OpLt:
+		return n < 0
+	}
+	return n <= 0
+}
+
+// Apply filter, sort and page list by query, value returns value of column of obj, used by in-memory fakes
+func (q *Query[T]) Apply(list []*T, value func(obj *T, column string) interface{}) []*T {
+	var matched []*T
+	for _, obj := range list {
+		if q.Match(obj, value) {
+			matched = append(matched, obj)
+		}
+	}
+	sort.SliceStable(matched, func(i, j int) bool {
+		for _, o := range q.orders {
+			n, _ := order(value(matched[i], o.Column), value(matched[j], o.Column))
+			if n != 0 {
+				return (n < 0) != o.Desc
+			}
+		}
+		return false
+	})
+	if q.offset >= len(matched) {
+		return nil
+	}
+	matched = matched[q.offset:]
+	if q.limit > 0 && q.limit < len(matched) {
+		matched = matched[:q.limit]
+	}
+	return matched
+}
+
+// compare 0 if a equals b
+func compare(a, b interface{}) int {
+	if n, ok := order(a, b); ok {
+		return n
+	}
+	if reflect.DeepEqual(a, b) {
+		return 0
+	}
+	return 1
+}
+
+// order compare a and b of number, string, bool or time, false if not ordered
+func order(a, b interface{}) (int, bool) {
+	if ta, ok := a.(time.Time); ok {
+		tb, ok := b.(time.Time)
+		if !ok {
+			return 0, false
+		}
+		return ta.Compare(tb), true
+	}
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	if !va.IsValid() || !vb.IsValid() || va.Kind() != vb.Kind() {
+		return 0, false
+	}
+	switch va.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return cmp.Compare(va.Int(), vb.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return cmp.Compare(va.Uint(), vb.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return cmp.Compare(va.Float(), vb.Float()), true
+	case reflect.String:
+		return cmp.Compare(va.String(), vb.String()), true
+	case reflect.Bool:
+		return cmp.Compare(boolInt(va.Bool()), boolInt(vb.Bool())), true
+	}
+	return 0, false
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,211 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type item struct {
+	ID        int
+	Name      string
+	Score     float64
+	Active    bool
+	CreatedAt time.Time
+}
+
+var items = struct {
+	ID        Column[int]
+	Name      Column[string]
+	Score     Column[float64]
+	Active    Column[bool]
+	CreatedAt Column[time.Time]
+	Unknown   Column[string]
+}{
+	ID:        Column[int]{Name: "id"},
+	Name:      Column[string]{Name: "name"},
+	Score:     Column[float64]{Name: "score"},
+	Active:    Column[bool]{Name: "active"},
+	CreatedAt: Column[time.Time]{Name: "created_at"},
+	Unknown:   Column[string]{Name: "unknown"},
+}
+
+func itemValue(obj *item, column string) interface{} {
+	switch column {
+	case "id":
+		return obj.ID
+	case "name":
+		return obj.Name
+	case "score":
+		return obj.Score
+	case "active":
+		return obj.Active
+	case "created_at":
+		return obj.CreatedAt
+	}
+	return nil
+}
+
+func TestQuerySQL(t *testing.T) {
+	cases := []struct {
+		q     *Query[item]
+		where string
+		args  []interface{}
+		order string
+	}{
+		{new(Query[item]), "", nil, ""},
+		{
+			new(Query[item]).Where(items.Name.Eq("a"), items.ID.Gt(1)).OrderBy(items.Score.Desc(), items.ID.Asc()),
+			`"name" = ? AND "id" > ?`, []interface{}{"a", 1}, `"score" DESC, "id"`,
+		},
+		{
+			new(Query[item]).Where(items.ID.In(1, 2), items.Name.NotIn("x"), items.Name.IsNull(), items.Score.NotNull()),
+			`"id" IN (?) AND "name" NOT IN (?) AND "name" IS NULL AND "score" IS NOT NULL`,
+			[]interface{}{[]int{1, 2}, []string{"x"}}, "",
+		},
+		{
+			new(Query[item]).Where(Or(items.ID.Lte(1), items.Score.Gte(2.5)), items.Name.Ne("a")),
+			`("id" <= ? OR "score" >= ?) AND "name" <> ?`, []interface{}{1, 2.5, "a"}, "",
+		},
+		{new(Query[item]).Where(Or()), "FALSE", nil, ""},
+		{new(Query[item]).Where(Or(Or(), items.ID.Lt(3))), `(FALSE OR "id" < ?)`, []interface{}{3}, ""},
+	}
+	for _, c := range cases {
+		where, args := c.q.SQL()
+		if where != c.where || !reflect.DeepEqual(args, c.args) || c.q.OrderSQL() != c.order {
+			t.Fatalf("%s %v %s, want %s %v %s", where, args, c.q.OrderSQL(), c.where, c.args, c.order)
+		}
+	}
+
+	q := new(Query[item]).Offset(2).Limit(3).OrderBy(items.ID.Desc())
+	if offset, limit := q.Page(); offset != 2 || limit != 3 || !reflect.DeepEqual(q.Orders(), []Order{{Column: "id", Desc: true}}) {
+		t.Fatal(offset, limit, q.Orders())
+	}
+	if fields := Fields(items.Name.Set("b"), items.Score.Set(1.5)); !reflect.DeepEqual(fields, map[string]interface{}{"name": "b", "score": 1.5}) {
+		t.Fatal(fields)
+	}
+}
+
+func TestQueryFilter(t *testing.T) {
+	type m = map[string]interface{}
+	cases := []struct {
+		q      *Query[item]
+		filter m
+	}{
+		{new(Query[item]), m{}},
+		{new(Query[item]).Where(items.Name.Eq("a")), m{"name": m{"$eq": "a"}}},
+		{
+			new(Query[item]).Where(items.ID.In(1, 2), items.Name.IsNull(), items.Score.NotNull()),
+			m{"$and": []interface{}{m{"id": m{"$in": []int{1, 2}}}, m{"name": nil}, m{"score": m{"$ne": nil}}}},
+		},
+		{
+			new(Query[item]).Where(Or(items.ID.Lt(1), items.ID.Gte(5))),
+			m{"$or": []interface{}{m{"id": m{"$lt": 1}}, m{"id": m{"$gte": 5}}}},
+		},
+		{new(Query[item]).Where(Or()), m{"_id": m{"$in": []interface{}{}}}},
+	}
+	for _, c := range cases {
+		if filter := c.q.Filter(); !reflect.DeepEqual(filter, c.filter) {
+			t.Fatalf("%v, want %v", filter, c.filter)
+		}
+	}
+}
+
+func TestQueryMatch(t *testing.T) {
+	now := time.Now()
+	obj := &item{ID: 2, Name: "b", Score: 1.5, Active: true, CreatedAt: now}
+	empty := &item{}
+	cases := []struct {
+		cond  Cond
+		obj   *item
+		match bool
+	}{
+		{items.ID.Eq(2), obj, true},
+		{items.ID.Ne(2), obj, false},
+		{items.ID.Gt(1), obj, true},
+		{items.ID.Gt(2), obj, false},
+		{items.ID.Gte(2), obj, true},
+		{items.Score.Lt(1.5), obj, false},
+		{items.Score.Lte(1.5), obj, true},
+		{items.Name.Gt("a"), obj, true},
+		{items.Active.Gt(false), obj, true},
+		{items.CreatedAt.Lt(now.Add(time.Second)), obj, true},
+		{items.CreatedAt.Eq(now), obj, true},
+		{items.ID.In(1, 2), obj, true},
+		{items.ID.In(), obj, false},
+		{items.ID.NotIn(1, 2), obj, false},
+		{items.ID.NotIn(), obj, true},
+		{Or(items.ID.Eq(1), items.Name.Eq("b")), obj, true},
+		{Or(items.ID.Eq(1), items.Name.Eq("c")), obj, false},
+		{Or(), obj, false},
+		// 零值为null
+		{items.Name.IsNull(), empty, true},
+		{items.Name.NotNull(), empty, false},
+		{items.CreatedAt.IsNull(), empty, true},
+		{items.Name.IsNull(), obj, false},
+		{items.Name.NotNull(), obj, true},
+		{items.Unknown.IsNull(), obj, true},
+		// 未知列无法比较大小
+		{items.Unknown.Gt("a"), obj, false},
+		{items.Unknown.Lte("a"), obj, false},
+		{items.Unknown.Eq("a"), obj, false},
+	}
+	for i, c := range cases {
+		q := new(Query[item]).Where(c.cond)
+		if q.Match(c.obj, itemValue) != c.match {
+			t.Fatalf("case %d: %+v of %+v, want %v", i, c.cond, c.obj, c.match)
+		}
+	}
+	if !new(Query[item]).Match(obj, itemValue) {
+		t.Fatal("query without condition must match")
+	}
+	if new(Query[item]).Where(items.ID.Eq(2), items.Name.Eq("c")).Match(obj, itemValue) {
+		t.Fatal("conditions are combined with AND")
+	}
+}
+
+func TestQueryApply(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	list := []*item{
+		{ID: 1, Name: "a", Score: 2, CreatedAt: base},
+		{ID: 2, Name: "b", Score: 1, CreatedAt: base.Add(time.Hour)},
+		{ID: 3, Name: "c", Score: 2, CreatedAt: base.Add(2 * time.Hour)},
+		{ID: 4, Name: "", Score: 3},
+		{ID: 5, Name: "e", Score: 1, CreatedAt: base.Add(-time.Hour)},
+	}
+	ids := func(list []*item) []int {
+		result := []int{}
+		for _, v := range list {
+			result = append(result, v.ID)
+		}
+		return result
+	}
+	cases := []struct {
+		q   *Query[item]
+		ids []int
+	}{
+		{new(Query[item]), []int{1, 2, 3, 4, 5}},
+		{new(Query[item]).OrderBy(items.ID.Desc()), []int{5, 4, 3, 2, 1}},
+		// 多列排序, 相等时保持原顺序
+		{new(Query[item]).OrderBy(items.Score.Desc(), items.Name.Asc()), []int{4, 1, 3, 2, 5}},
+		{new(Query[item]).OrderBy(items.Score.Asc()), []int{2, 5, 1, 3, 4}},
+		{new(Query[item]).OrderBy(items.CreatedAt.Asc()), []int{4, 5, 1, 2, 3}},
+		{new(Query[item]).Where(items.Name.NotNull()).OrderBy(items.CreatedAt.Desc()), []int{3, 2, 1, 5}},
+		{new(Query[item]).Where(items.Score.Gte(2)).OrderBy(items.ID.Asc()).Offset(1).Limit(1), []int{3}},
+		{new(Query[item]).OrderBy(items.ID.Asc()).Offset(3), []int{4, 5}},
+		{new(Query[item]).OrderBy(items.ID.Asc()).Limit(2), []int{1, 2}},
+		{new(Query[item]).Limit(10), []int{1, 2, 3, 4, 5}},
+		{new(Query[item]).Limit(-1), []int{1, 2, 3, 4, 5}},
+		{new(Query[item]).Offset(5), []int{}},
+		{new(Query[item]).Offset(10).Limit(1), []int{}},
+		{new(Query[item]).Where(Or()), []int{}},
+	}
+	for i, c := range cases {
+		if got := ids(c.q.Apply(list, itemValue)); !reflect.DeepEqual(got, c.ids) {
+			t.Fatalf("case %d: %v, want %v", i, got, c.ids)
+		}
+	}
+	if list[0].ID != 1 || list[4].ID != 5 {
+		t.Fatal("input list is modified")
+	}
+}
@@ -0,0 +1,27 @@
+package db
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	// ErrTenantRequired tenant is not found in context of tenant-scoped dao
+	ErrTenantRequired = errors.New("tenant is required in context")
+	// ErrCrossTenant write object of other tenant
+	ErrCrossTenant = errors.New("cross-tenant write is refused")
+)
+
+type tenantKey struct{}
+
+// WithTenant context with tenant, used by generated dao of table with tenant column
+func WithTenant[T comparable](ctx context.Context, tenant T) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFrom tenant of context, false if not found, type mismatch or zero value
+func TenantFrom[T comparable](ctx context.Context) (T, bool) {
+	var zero T
+	tenant, ok := ctx.Value(tenantKey{}).(T)
+	return tenant, ok && tenant != zero
+}
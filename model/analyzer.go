@@ -4,7 +4,9 @@ package model
 import (
 	"bytes"
 	"errors"
+	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/iancoleman/strcase"
@@ -37,7 +39,7 @@ var sqlTypeToGo = map[string]string{
 func ddlAnalyzer(raw []byte) (params *commandParams, err error) {
 	params = &commandParams{}
 
-	data := bytesSplitAndTrimSpace(raw, []byte(";"))
+	data := splitStatements(raw)
 	for _, v := range data {
 		ddl := string(v)
 		if ddl == "" {
@@ -55,10 +57,8 @@ func ddlAnalyzer(raw []byte) (params *commandParams, err error) {
 		fields := m.Fields(line)
 		switch strings.ToUpper(fields[0]) {
 		case "CREATE":
-			if len(fields) > 1 && strings.ToUpper(fields[1]) == "INDEX" {
+			if len(fields) > 1 && (strings.ToUpper(fields[1]) == "INDEX" || strings.ToUpper(fields[1]) == "UNIQUE") {
 				err = parseCreateIndex(m, params)
-			} else if len(fields) > 1 && strings.ToUpper(fields[1]) == "UNIQUE" {
-				err = parseCreateUniqueIndex(m, params)
 			} else {
 				err = parseCreate(m, params)
 			}
@@ -72,7 +72,8 @@ func ddlAnalyzer(raw []byte) (params *commandParams, err error) {
 			return nil, errors.New("unknown marker action: " + fields[0])
 		}
 		if err != nil {
-			return nil, err
+			// 返回已解析的部分, 供lint报告
+			return params, err
 		}
 	}
 	// params
@@ -84,218 +85,554 @@ func parseCreate(m *marker, params *commandParams) error {
 	line = strings.Replace(line, "IF NOT EXISTS", "", 1)
 	fields := m.Fields(line)
 
-	switch strings.ToUpper(fields[1]) {
+	// 跳过可选的修饰词, eg. CREATE OR REPLACE VIEW, CREATE TEMP TABLE
+	i := 1
+	for i < len(fields)-1 && createModifiers[strings.ToUpper(fields[i])] {
+		i++
+	}
+	switch strings.ToUpper(fields[i]) {
 	case "TABLE":
-		params.TableName = strcase.ToCamel(fields[2])
+		if i+1 >= len(fields) {
+			return errors.New("invalid ddl: " + line)
+		}
+		params.TableName = strcase.ToCamel(fields[i+1])
 		err := parseFields(m, params)
 		if err != nil {
 			return err
 		}
+		return typeFields(params, fields[i+1])
+	case "VIEW", "MATERIALIZED":
+		return parseCreateView(m, params)
+	case "TYPE":
+		return parseCreateType(m, params)
+	}
+	return errors.New("unsupported operation: CREATE " + strings.Join(fields[1:i+1], " "))
+}
 
-		// rebuild tag
-		rebuildTag := func(f *field) {
-			// other field
-			f.Tag = "column:" + f.Name
-			if f.defaultVal != "" {
-				f.Tag += ";default:" + f.defaultVal
-			}
-			if f.notNull {
-				f.Tag += ";not null"
-			}
-			// 计算idx - 只处理从表结构中解析的索引
-			for _, idx := range f.indexs {
-				var idxStr string
-				for i, v := range idx.indexFields {
-					if i == 0 {
-						idxStr = "idx_" + fields[2]
-					}
-					idxStr += "_" + strcase.ToSnakeWithIgnore(v.Name, "sha1")
-				}
-				if idx.normalIndex {
-					f.Tag += ";index:" + idxStr
-				}
-				if idx.uniqueIndex {
-					f.Tag += ";uniqueIndex:" + idxStr
+// createModifiers 可选的CREATE修饰词
+var createModifiers = map[string]bool{
+	"OR":        true,
+	"REPLACE":   true,
+	"TEMP":      true,
+	"TEMPORARY": true,
+	"UNLOGGED":  true,
+}
+
+// typeFields 映射字段的go类型并生成gorm tag, table为DDL表名
+func typeFields(params *commandParams, table string) error {
+	var err error
+	// rebuild tag
+	rebuildTag := func(f *field) {
+		// other field
+		f.Tag = "column:" + f.Name
+		if f.defaultVal != "" {
+			f.Tag += ";default:" + f.defaultVal
+		}
+		if f.notNull {
+			f.Tag += ";not null"
+		}
+		// 计算idx - 只处理从表结构中解析的索引
+		for _, idx := range f.indexs {
+			var idxStr string
+			for i, v := range idx.indexFields {
+				if i == 0 {
+					idxStr = "idx_" + table
 				}
+				idxStr += "_" + strcase.ToSnakeWithIgnore(v.Name, "sha1")
 			}
-			if f.createdAt {
-				f.Tag += ";autoCreateTime"
+			if idx.normalIndex {
+				f.Tag += ";index:" + idxStr
 			}
-			if f.updatedAt {
-				f.Tag += ";autoUpdateTime"
+			if idx.uniqueIndex {
+				f.Tag += ";uniqueIndex:" + idxStr
 			}
-			if f.Type == "[]string" || f.Type == "[]int" {
-				f.Tag += ";serializer:json"
-			}
-			// change name
-			f.Name = strcase.ToCamel(f.Name)
 		}
+		if f.createdAt {
+			f.Tag += ";autoCreateTime"
+		}
+		if f.updatedAt {
+			f.Tag += ";autoUpdateTime"
+		}
+		if f.Type == "[]string" || f.Type == "[]int" {
+			f.Tag += ";serializer:json"
+		}
+		// change name
+		f.Name = strcase.ToCamel(f.Name)
+	}
 
-		// primary key
-		if params.Primary != nil {
-			rebuildTag(params.Primary.field)
-			params.Primary.Tag += ";primaryKey"
-			if params.Primary.Autoincrement {
-				params.Primary.Tag += ";autoIncrement"
-			}
+	// primary key
+	if params.Primary != nil {
+		rebuildTag(params.Primary.field)
+		params.Primary.Tag += ";primaryKey"
+		if params.Primary.Autoincrement {
+			params.Primary.Tag += ";autoIncrement"
 		}
+	}
 
-		// change content
-		for _, v := range params.Fields {
-			vt := strings.ToUpper(v.Type)
-			// replace type
-			ok := false
-			for key, val := range sqlTypeToGo {
-				// 判断filed是否位deleted
-				if v.Name == "deleted_at" {
-					v.Type = "gorm.DeletedAt"
-					ok = true
-					break
-				}
-				// 映射数据类型
-				if vt == key {
-					v.Type = val
-					ok = true
-					break
-				}
+	// change content
+	for _, v := range params.Fields {
+		vt := strings.ToUpper(v.Type)
+		// 去掉长度, eg. VARCHAR(1024)
+		if i := strings.Index(vt, "("); i > 0 {
+			vt = vt[:i]
+		}
+		// replace type
+		ok := false
+		// CREATE TYPE ... AS ENUM
+		if values, isEnum := params.enums[vt]; isEnum {
+			v.Type = "string"
+			v.enum = values
+			ok = true
+		}
+		for key, val := range sqlTypeToGo {
+			if ok {
+				break
 			}
-			if !ok {
-				return errors.New("unsupported pg type to go:" + v.Type)
+			// 判断filed是否位deleted
+			if v.Name == "deleted_at" {
+				v.Type = "gorm.DeletedAt"
+				ok = true
+				break
 			}
-			// rebuild tag
-			if v.Tag == "" {
-				rebuildTag(v)
+			// 映射数据类型
+			if vt == key {
+				v.Type = val
+				ok = true
+				break
 			}
 		}
-		return nil
+		if !ok {
+			params.issues = append(params.issues, &Issue{
+				Table:    strcase.ToSnake(params.TableName),
+				Column:   v.column,
+				Rule:     LintUnsupportedType,
+				Severity: SeverityError,
+				Message:  "unsupported type " + v.Type,
+			})
+			if err == nil {
+				err = fmt.Errorf("%w:%s", errUnsupportedType, v.Type)
+			}
+			continue
+		}
+		// rebuild tag
+		if v.Tag == "" {
+			rebuildTag(v)
+		}
 	}
-	return errors.New("unsupported operation: CREATE " + fields[1])
+	// 所有不支持的类型都记录到issues后返回
+	return err
 }
 
-// parseCreateIndex 解析 CREATE INDEX 语句
-func parseCreateIndex(m *marker, params *commandParams) error {
-	line := m.currentLine()
-	// CREATE INDEX index_name ON table_name (column1, column2, ...)
+var (
+	regexpCreateView = regexp.MustCompile(`(?is)^CREATE\s+(?:OR\s+REPLACE\s+)?(?:TEMP(?:ORARY)?\s+)?(MATERIALIZED\s+)?VIEW\s+(?:IF\s+NOT\s+EXISTS\s+)?(?:"?\w+"?\.)?"?(\w+)"?\s*(\()?`)
+	regexpViewAs     = regexp.MustCompile(`(?is)^\s*AS\b`)
+)
 
-	// 使用正则表达式解析索引语句
-	indexRegex := regexp.MustCompile(`CREATE\s+INDEX\s+(\w+)\s+ON\s+(\w+)\s*\(([^)]+)\)`)
-	matches := indexRegex.FindStringSubmatch(line)
+// parseCreateView 解析 CREATE [MATERIALIZED] VIEW, 需显式列出列及其类型, 类型位于列名后或行尾注释, eg.
+//
+//	CREATE MATERIALIZED VIEW user_stats (
+//	    user_id,     -- BIGINT NOT NULL
+//	    login_count  -- INTEGER
+//	) AS SELECT user_id, count(*) FROM user_login GROUP BY user_id
+func parseCreateView(m *marker, params *commandParams) error {
+	var (
+		lines    []string
+		comments = make(map[string]string) // 列名 -> 行尾注释
+	)
+	for _, line := range m.linesDDL[m.lineIndex:] {
+		code, comment, _ := strings.Cut(line, "--")
+		code = strings.TrimSpace(code)
+		if code == "" {
+			continue
+		}
+		lines = append(lines, code)
+		if fields := m.Fields(strings.Trim(code, "(),")); len(fields) > 0 && strings.TrimSpace(comment) != "" {
+			comments[strings.TrimSuffix(fields[0], ",")] = strings.TrimSpace(comment)
+		}
+	}
+	ddl := strings.Join(lines, "\n")
+	head := regexpCreateView.FindStringSubmatchIndex(ddl)
+	if head == nil {
+		return errors.New("invalid CREATE VIEW statement: " + ddl)
+	}
+	name := ddl[head[4]:head[5]]
+	if head[6] < 0 {
+		return fmt.Errorf("view %s requires explicit column list, eg. CREATE VIEW %s (id, -- INTEGER NOT NULL", name, name)
+	}
+	end := closeParen(ddl, head[6])
+	if end < 0 || !regexpViewAs.MatchString(ddl[end+1:]) {
+		return errors.New("invalid CREATE VIEW statement: " + ddl)
+	}
+	params.TableName = strcase.ToCamel(name)
+	params.View = true
+	params.Materialized = head[2] >= 0
 
-	if len(matches) != 4 {
-		return errors.New("invalid CREATE INDEX statement: " + line)
+	for _, elem := range splitTopLevel(ddl[head[6]+1 : end]) {
+		fields := m.Fields(elem)
+		if len(fields) == 0 {
+			continue
+		}
+		column, typ := fields[0], fields[1:]
+		if len(typ) == 0 {
+			typ = strings.Fields(comments[column])
+		}
+		if len(typ) == 0 {
+			return fmt.Errorf("missing type of view column %s, eg. %s, -- INTEGER NOT NULL", column, column)
+		}
+		f := &field{
+			Name:    column,
+			Type:    typ[0],
+			column:  column,
+			sqlType: strings.ToUpper(typ[0]),
+		}
+		for i := 1; i+1 < len(typ); i++ {
+			if strings.EqualFold(typ[i], "NOT") && strings.EqualFold(typ[i+1], "NULL") {
+				f.notNull = true
+			}
+		}
+		params.Fields = append(params.Fields, f)
 	}
+	return typeFields(params, name)
+}
 
-	indexName := matches[1]
-	tableName := matches[2]
-	columnList := matches[3]
+var regexpEnumValue = regexp.MustCompile(`'((?:[^']|'')*)'`)
 
-	// 检查表名是否匹配
-	if params.TableName != "" && strings.ToLower(tableName) != strings.ToLower(strcase.ToSnake(params.TableName)) {
-		// 如果表名不匹配，可能是另一个表的索引，忽略
-		return nil
+// enumValues 解析 'a', 'b'
+func enumValues(list string) []string {
+	var values []string
+	for _, v := range regexpEnumValue.FindAllStringSubmatch(list, -1) {
+		values = append(values, strings.ReplaceAll(v[1], "''", "'"))
 	}
+	return values
+}
 
-	// 解析列名
-	columns := strSplitAndTrimSpace(columnList, ",")
-	var indexFields []*field
+// parseCreateType 解析 CREATE TYPE name AS ENUM ('a', 'b')
+func parseCreateType(m *marker, params *commandParams) error {
+	ddl := strings.Join(m.linesDDL, " ")
+	fields := m.Fields(ddl)
+	if len(fields) < 5 || strings.ToUpper(fields[3]) != "AS" ||
+		!strings.HasPrefix(strings.ToUpper(fields[4]), "ENUM") {
+		return errors.New("unsupported operation: " + ddl)
+	}
+	i := strings.Index(ddl, "(")
+	if i < 0 {
+		return errors.New("invalid ddl: " + ddl)
+	}
+	if params.enums == nil {
+		params.enums = make(map[string][]string)
+	}
+	params.enums[strings.ToUpper(fields[2])] = enumValues(ddl[i:])
+	return nil
+}
 
-	for _, col := range columns {
-		col = strings.TrimSpace(col)
-		// 查找对应的字段
-		for _, f := range params.Fields {
-			if strings.EqualFold(strcase.ToSnake(f.Name), col) {
-				indexFields = append(indexFields, f)
-				break
+var (
+	regexpCreateIndex = regexp.MustCompile(`(?is)^CREATE\s+(UNIQUE\s+)?INDEX\s+(CONCURRENTLY\s+)?(?:IF\s+NOT\s+EXISTS\s+)?(?:"?(\w+)"?\s+)?ON\s+(?:ONLY\s+)?(?:"?\w+"?\.)?"?(\w+)"?\s*(?:USING\s+(\w+)\s*)?\(`)
+	regexpIndexTail   = regexp.MustCompile(`(?is)^\s*(?:INCLUDE\s*\(([^)]*)\))?\s*(?:WITH\s*\([^)]*\))?\s*(?:TABLESPACE\s+\w+)?\s*(?:WHERE\s+(.+))?$`)
+	regexpIndexSort   = regexp.MustCompile(`(?i)\b(ASC|DESC)?\s*(NULLS\s+(?:FIRST|LAST))?\s*$`)
+	regexpIdentifier  = regexp.MustCompile(`"?\b([A-Za-z_]\w*)\b"?`)
+	regexpMongoOption = regexp.MustCompile(`(?i)^--\s*mongo:\s*(.*)$`)
+	regexpCommentText = regexp.MustCompile(`(?i)^COMMENT\s+ON\s+.*?\s+IS\s+(.*)$`)
+)
+
+// closeParen 与s[start]的 "(" 匹配的 ")" 的位置, 不存在时为-1
+func closeParen(s string, start int) int {
+	depth, quoted := 0, false
+	for i := start; i < len(s); i++ {
+		switch {
+		case s[i] == '\'':
+			quoted = !quoted
+		case quoted:
+		case s[i] == '(':
+			depth++
+		case s[i] == ')':
+			depth--
+			if depth == 0 {
+				return i
 			}
 		}
 	}
+	return -1
+}
 
-	if len(indexFields) == 0 {
-		return nil // 没有找到对应的字段
+// splitTopLevel 按不在括号及引号内的逗号分割
+func splitTopLevel(s string) []string {
+	var (
+		list         []string
+		depth, start int
+		quoted       bool
+	)
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\'':
+			quoted = !quoted
+		case quoted:
+		case s[i] == '(':
+			depth++
+		case s[i] == ')':
+			depth--
+		case s[i] == ',' && depth == 0:
+			list = append(list, strings.TrimSpace(s[start:i]))
+			start = i + 1
+		}
 	}
+	return append(list, strings.TrimSpace(s[start:]))
+}
 
-	// 创建索引信息
-	idx := index{
-		normalIndex: true,
-		uniqueIndex: false,
-		indexFields: indexFields,
-		indexName:   indexName,
+// parseIndexElement 解析索引元素, eg. email DESC NULLS LAST, lower(email), (a + b),
+// multi为表达式引用了多个列
+func parseIndexElement(elem string, params *commandParams) (f *field, expr, sort string, multi bool, err error) {
+	// 排序
+	if sub := regexpIndexSort.FindStringSubmatchIndex(elem); sub != nil && sub[1] > sub[0] {
+		sort = strings.ToUpper(strings.Join(strings.Fields(elem[sub[0]:sub[1]]), " "))
+		elem = strings.TrimSpace(elem[:sub[0]])
 	}
 
-	// 将索引添加到相关字段
-	for _, f := range indexFields {
-		f.indexs = append(f.indexs, idx)
-		// 更新字段的tag
-		if !strings.Contains(f.Tag, ";index:"+indexName) {
-			f.Tag += ";index:" + indexName
+	// 表达式
+	if i := strings.Index(elem, "("); i >= 0 {
+		end := closeParen(elem, i)
+		if end < 0 {
+			return nil, "", "", false, errors.New("invalid index expression: " + elem)
+		}
+		expr = strings.TrimSpace(elem[:end+1])
+		if i == 0 {
+			expr = strings.TrimSpace(expr[1:end])
+		}
+		// 表达式所在字段为引用的第一个列
+		for _, sub := range regexpIdentifier.FindAllStringSubmatch(expr, -1) {
+			for _, v := range params.Fields {
+				if !strings.EqualFold(v.column, sub[1]) {
+					continue
+				}
+				if f == nil {
+					f = v
+				} else if f != v {
+					multi = true
+				}
+			}
 		}
+		return f, expr, sort, multi, nil
 	}
 
-	return nil
+	// 列名, 忽略COLLATE及opclass
+	if elem == "" {
+		return nil, "", sort, false, nil
+	}
+	column := strings.Trim(strings.Fields(elem)[0], `"`)
+	for _, v := range params.Fields {
+		if strings.EqualFold(v.column, column) {
+			return v, "", sort, false, nil
+		}
+	}
+	return nil, "", sort, false, nil
 }
 
-// parseCreateUniqueIndex 解析 CREATE UNIQUE INDEX 语句
-func parseCreateUniqueIndex(m *marker, params *commandParams) error {
-	line := m.currentLine()
-	// CREATE UNIQUE INDEX index_name ON table_name (column1, column2, ...)
-
-	// 使用正则表达式解析唯一索引语句
-	indexRegex := regexp.MustCompile(`CREATE\s+UNIQUE\s+INDEX\s+(\w+)\s+ON\s+(\w+)\s*\(([^)]+)\)`)
-	matches := indexRegex.FindStringSubmatch(line)
-
-	if len(matches) != 4 {
-		return errors.New("invalid CREATE UNIQUE INDEX statement: " + line)
+// parseCreateIndex 解析 CREATE INDEX 语句, eg.
+//
+//	-- mongo: sparse, expireAfterSeconds=3600
+//	CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS index_name ON table_name USING btree
+//	    (column1 DESC NULLS LAST, lower(column2)) INCLUDE (column3) WHERE deleted_at IS NULL
+//
+// 语句前的 "-- mongo:" 注释为mongodb索引选项: sparse, text, expireAfterSeconds=N
+func parseCreateIndex(m *marker, params *commandParams) error {
+	var (
+		lines   []string
+		options []string
+	)
+	for i, line := range m.linesDDL {
+		line = strings.TrimSpace(line)
+		if i < m.lineIndex {
+			if sub := regexpMongoOption.FindStringSubmatch(line); len(sub) == 2 {
+				options = append(options, strings.FieldsFunc(sub[1], func(r rune) bool {
+					return r == ',' || r == ' '
+				})...)
+			}
+			continue
+		}
+		if !strings.HasPrefix(line, "--") {
+			lines = append(lines, line)
+		}
 	}
-
-	indexName := matches[1]
-	tableName := matches[2]
-	columnList := matches[3]
+	ddl := strings.Join(lines, " ")
+	head := regexpCreateIndex.FindStringSubmatchIndex(ddl)
+	if head == nil {
+		return errors.New("invalid CREATE INDEX statement: " + ddl)
+	}
+	group := func(i int) string {
+		if head[2*i] < 0 {
+			return ""
+		}
+		return ddl[head[2*i]:head[2*i+1]]
+	}
+	end := closeParen(ddl, head[1]-1)
+	if end < 0 {
+		return errors.New("invalid CREATE INDEX statement: " + ddl)
+	}
+	tail := regexpIndexTail.FindStringSubmatch(ddl[end+1:])
+	if tail == nil {
+		return errors.New("invalid CREATE INDEX statement: " + ddl)
+	}
+	unique := group(1) != ""
+	indexName := group(3)
+	tableName := group(4)
 
 	// 检查表名是否匹配
 	if params.TableName != "" && strings.ToLower(tableName) != strings.ToLower(strcase.ToSnake(params.TableName)) {
 		// 如果表名不匹配，可能是另一个表的索引，忽略
+		params.issues = append(params.issues, &Issue{
+			Table:    strings.ToLower(strings.Trim(tableName, `"`)),
+			Index:    indexName,
+			Rule:     LintForeignIndex,
+			Severity: SeverityWarning,
+			Message:  fmt.Sprintf("index %s on table %s is ignored, it must be in the file of the table", indexName, tableName),
+		})
 		return nil
 	}
 
-	// 解析列名
-	columns := strSplitAndTrimSpace(columnList, ",")
-	var indexFields []*field
+	// 创建索引信息
+	idx := index{
+		normalIndex:  !unique,
+		uniqueIndex:  unique,
+		indexName:    indexName,
+		using:        strings.ToLower(group(5)),
+		concurrently: group(2) != "",
+		where:        strings.TrimSpace(tail[2]),
+	}
+	if tail[1] != "" {
+		for _, v := range strSplitAndTrimSpace(tail[1], ",") {
+			idx.include = append(idx.include, strings.Trim(v, `"`))
+		}
+	}
+	for _, v := range options {
+		switch kv := strings.SplitN(v, "=", 2); strings.ToLower(kv[0]) {
+		case "sparse":
+			idx.sparse = true
+		case "text":
+			idx.text = true
+		case "expireafterseconds":
+			if len(kv) != 2 {
+				return errors.New("invalid mongo index option: " + v)
+			}
+			seconds, err := strconv.ParseInt(kv[1], 10, 32)
+			if err != nil {
+				return errors.New("invalid mongo index option: " + v)
+			}
+			idx.ttl = true
+			idx.expireAfterSeconds = int32(seconds)
+		default:
+			return errors.New("unknown mongo index option: " + v)
+		}
+	}
 
-	for _, col := range columns {
-		col = strings.TrimSpace(col)
-		// 查找对应的字段
-		for _, f := range params.Fields {
-			if strings.EqualFold(strcase.ToSnake(f.Name), col) {
-				indexFields = append(indexFields, f)
-				break
+	// 解析索引元素, eg. column1 DESC, lower(column2)
+	for _, elem := range splitTopLevel(ddl[head[1]:end]) {
+		f, expr, sort, multi, err := parseIndexElement(elem, params)
+		if err != nil {
+			return err
+		}
+		idx.multiColumnExpr = idx.multiColumnExpr || multi
+		if f == nil {
+			if elem != "" {
+				params.issues = append(params.issues, &Issue{
+					Table:    strcase.ToSnake(params.TableName),
+					Index:    indexName,
+					Rule:     LintUnknownColumn,
+					Severity: SeverityError,
+					Message:  fmt.Sprintf("index %s references unknown column %s", indexName, elem),
+				})
 			}
+			continue // 没有找到对应的字段
 		}
+		idx.indexFields = append(idx.indexFields, f)
+		idx.exprs = append(idx.exprs, expr)
+		idx.sorts = append(idx.sorts, sort)
 	}
 
-	if len(indexFields) == 0 {
+	if len(idx.indexFields) == 0 {
+		params.issues = append(params.issues, &Issue{
+			Table:    strcase.ToSnake(params.TableName),
+			Index:    indexName,
+			Rule:     LintUnknownColumn,
+			Severity: SeverityError,
+			Message:  fmt.Sprintf("index %s has no known column and is ignored", indexName),
+		})
 		return nil // 没有找到对应的字段
 	}
-
-	// 创建唯一索引信息
-	idx := index{
-		normalIndex: false,
-		uniqueIndex: true,
-		indexFields: indexFields,
-		indexName:   indexName,
+	// 未命名索引, 与postgres默认命名不同, 与rebuildTag一致
+	if idx.indexName == "" {
+		idx.indexName = "idx_" + strings.ToLower(tableName)
+		for _, f := range idx.indexFields {
+			idx.indexName += "_" + f.column
+		}
+	}
+	// 同名索引在生成时会合并
+	if hasIndex(params.Fields, idx.indexName) {
+		params.issues = append(params.issues, &Issue{
+			Table:    strcase.ToSnake(params.TableName),
+			Index:    idx.indexName,
+			Rule:     LintDuplicateIndex,
+			Severity: SeverityError,
+			Message:  "duplicate index name " + idx.indexName,
+		})
 	}
 
 	// 将索引添加到相关字段
-	for _, f := range indexFields {
+	for i, f := range idx.indexFields {
 		f.indexs = append(f.indexs, idx)
 		// 更新字段的tag
-		if !strings.Contains(f.Tag, ";uniqueIndex:"+indexName) {
-			f.Tag += ";uniqueIndex:" + indexName
+		tag := gormIndexTag(idx, i)
+		if !strings.Contains(f.Tag, tag) {
+			f.Tag += tag
 		}
 	}
 
 	return nil
 }
 
+// hasIndex whether any field has index of name
+func hasIndex(fields []*field, name string) bool {
+	for _, f := range fields {
+		for _, idx := range f.indexs {
+			if idx.indexName == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// gormTagEscape 转义gorm索引选项中的逗号及struct tag中的引号
+var gormTagEscape = strings.NewReplacer(`\`, `\\`, `"`, `\"`, ",", `\\,`)
+
+// gormCommentEscape 转义gorm tag中的分号及struct tag中的引号
+var gormCommentEscape = strings.NewReplacer(`\`, `\\`, `"`, `\"`, ";", `\\;`)
+
+// gormIndexTag gorm tag of index field, eg. ;index:idx_user_email,type:gin,where:deleted_at IS NULL
+func gormIndexTag(idx index, i int) string {
+	tag := ";index:" + idx.indexName
+	if idx.uniqueIndex {
+		tag = ";uniqueIndex:" + idx.indexName
+	}
+	if idx.using != "" {
+		tag += ",type:" + idx.using
+	}
+	if idx.concurrently {
+		tag += ",option:CONCURRENTLY"
+	}
+	if idx.where != "" {
+		tag += ",where:" + gormTagEscape.Replace(idx.where)
+	}
+	if idx.exprs[i] != "" {
+		tag += ",expression:" + gormTagEscape.Replace(idx.exprs[i])
+	}
+	if idx.sorts[i] != "" {
+		tag += ",sort:" + strings.ToLower(idx.sorts[i])
+	}
+	if len(idx.indexFields) > 1 {
+		tag += ",priority:" + strconv.Itoa(i+1)
+	}
+	return tag
+}
+
 func parseComment(m *marker, params *commandParams) error {
 	line := m.currentLine()
 
@@ -303,21 +640,41 @@ func parseComment(m *marker, params *commandParams) error {
 	if len(fields) < 6 {
 		return errors.New("invalid ddl: " + line)
 	}
+	// COMMENT ON MATERIALIZED VIEW name IS ...
+	if strings.ToUpper(fields[2]) == "MATERIALIZED" {
+		fields = append(fields[:2:2], fields[3:]...)
+	}
 	column := fields[3]
 	// NOTE 统一sql文件只能操作同一个表，这里不判断表
 	if sli := strSplitAndTrimSpace(fields[3], "."); len(sli) > 1 {
 		column = sli[1]
 	}
 	comment := strings.Join(fields[5:], " ")
+	if sub := regexpCommentText.FindStringSubmatch(line); sub != nil {
+		comment = sub[1] // 保留注释中的双引号和空格
+	}
+	if kind := strings.ToUpper(fields[2]); kind == "TABLE" || kind == "VIEW" {
+		params.Comment = commentText(comment)
+		return nil
+	}
 
 	idx := foundFiled(params.Fields, strcase.ToCamel(column))
 	if idx > -1 {
-		// params.Fields[idx].Comment = strings.Trim(comment, "'")
-		params.Fields[idx].Tag += ";comment:" + strings.Trim(comment, "'")
+		params.Fields[idx].Comment = commentText(comment)
+		params.Fields[idx].Tag += ";comment:" + gormCommentEscape.Replace(commentText(comment))
 	}
 	return nil
 }
 
+// commentText 去掉引号并还原转义的单引号
+func commentText(comment string) string {
+	comment = strings.TrimSpace(comment)
+	if len(comment) >= 2 && (comment[0] == '\'' || comment[0] == '"') && comment[len(comment)-1] == comment[0] {
+		comment = comment[1 : len(comment)-1]
+	}
+	return strings.ReplaceAll(comment, "''", "'")
+}
+
 func parseAlter(m *marker, params *commandParams) error {
 	// TODO
 	return nil
@@ -328,11 +685,35 @@ func parseDrop(m *marker, params *commandParams) error {
 	return nil
 }
 
-var regexpIndex = regexp.MustCompile(`\((.+?)\)`)
+var (
+	regexpIndex      = regexp.MustCompile(`\((.+?)\)`)
+	regexpCheckIn    = regexp.MustCompile(`(?i)CHECK\s*\(\s*"?\w+"?\s+IN\s*\(([^)]*)\)`)
+	regexpReferences = regexp.MustCompile(`(?i)\sREFERENCES\s+"?(\w+)"?\s*(?:\(([^)]+)\))?`)
+	regexpForeignKey = regexp.MustCompile(`(?i)^FOREIGN\s+KEY\s*\(([^)]+)\)`)
+)
+
+// parseReferences 解析 REFERENCES table (col, ...), 未指定列时为空
+func parseReferences(line string) (table string, columns []string, ok bool) {
+	sub := regexpReferences.FindStringSubmatch(line)
+	if len(sub) != 3 {
+		return "", nil, false
+	}
+	if sub[2] != "" {
+		for _, v := range strSplitAndTrimSpace(sub[2], ",") {
+			columns = append(columns, strings.Trim(v, "\""))
+		}
+	}
+	return sub[1], columns, true
+}
 
 func parseFields(m *marker, params *commandParams) error {
 	for line := m.nextLine(); line != ""; line = m.nextLine() {
 		fields := m.Fields(line)
+		// CONSTRAINT name FOREIGN KEY/UNIQUE/PRIMARY KEY ...
+		if strings.ToUpper(fields[0]) == "CONSTRAINT" && len(fields) > 2 {
+			fields = fields[2:]
+			line = strings.Join(fields, " ")
+		}
 
 		switch fields[0] {
 		case "PRIMARY":
@@ -356,14 +737,48 @@ func parseFields(m *marker, params *commandParams) error {
 				}
 			}
 		// 移除表内的 UNIQUE 和 INDEX 解析，这些现在通过 CREATE INDEX 语句处理
+		case "UNIQUE", "INDEX":
+			params.issues = append(params.issues, &Issue{
+				Table:    strcase.ToSnake(params.TableName),
+				Rule:     LintInlineIndex,
+				Severity: SeverityWarning,
+				Message:  "in-table " + strings.TrimRight(line, ",") + " is ignored, use CREATE INDEX instead",
+			})
+		case "FOREIGN": // 表内外键, eg. FOREIGN KEY (user_id) REFERENCES "user" (id)
+			sub := regexpForeignKey.FindStringSubmatch(line)
+			table, columns, ok := parseReferences(line)
+			if len(sub) != 2 || !ok {
+				return errors.New("invalid ddl:" + line)
+			}
+			fk := foreignKey{refTable: table, refColumns: columns}
+			for _, v := range strSplitAndTrimSpace(sub[1], ",") {
+				i := foundFiled(params.Fields, strings.Trim(v, "\""))
+				if i < 0 {
+					return errors.New("unknown column in ddl:" + line)
+				}
+				fk.fields = append(fk.fields, params.Fields[i])
+			}
+			params.foreignKeys = append(params.foreignKeys, fk)
 		default: // 普通字段
 			field := &field{
-				Name: fields[0],
-				Type: fields[1],
+				Name:    fields[0],
+				Type:    fields[1],
+				column:  fields[0],
+				sqlType: strings.ToUpper(fields[1]),
 			}
 			if i := foundFiled(fields, "DEFAULT"); i > 0 {
 				field.defaultVal = fields[i+1]
 			}
+			// CHECK (status IN ('a', 'b'))
+			if sub := regexpCheckIn.FindStringSubmatch(line); len(sub) == 2 {
+				field.enum = enumValues(sub[1])
+			}
+			// 列外键, eg. user_id INTEGER REFERENCES "user" (id)
+			if table, columns, ok := parseReferences(line); ok {
+				fk := foreignKey{refTable: table, refColumns: columns}
+				fk.fields = append(fk.fields, field)
+				params.foreignKeys = append(params.foreignKeys, fk)
+			}
 			if i := foundFiled(fields, "NOT"); i > 0 {
 				if fields[i+1] != "NULL" {
 					return errors.New("invalid ddl:" + line)
@@ -413,12 +828,29 @@ func strSplitAndTrimSpace(str string, sep string) []string {
 	return arr
 }
 
-func bytesSplitAndTrimSpace(raw []byte, sep []byte) [][]byte {
-	arr := bytes.Split(raw, sep)
-	for i, v := range arr {
-		arr[i] = bytes.TrimSpace(v)
+// splitStatements split raw by ";" outside of quotes and "--" comments, eg. COMMENT ON TABLE t IS 'a;b'
+func splitStatements(raw []byte) [][]byte {
+	var arr [][]byte
+	var quote byte // 所在引号, 0为引号外
+	start, comment := 0, false
+	for i := 0; i < len(raw); i++ {
+		switch c := raw[i]; {
+		case comment:
+			comment = c != '\n'
+		case quote != 0:
+			if c == quote {
+				quote = 0 // 转义的''视为两段相邻的字符串
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '-' && i+1 < len(raw) && raw[i+1] == '-':
+			comment = true
+		case c == ';':
+			arr = append(arr, bytes.TrimSpace(raw[start:i]))
+			start = i + 1
+		}
 	}
-	return arr
+	return append(arr, bytes.TrimSpace(raw[start:]))
 }
 
 type marker struct {
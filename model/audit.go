@@ -0,0 +1,66 @@
+// Package model provides ...
+package model
+
+import (
+	"fmt"
+	"strings"
+)
+
+// auditTable set audit columns and history of params, DAO methods take context.Context
+// if the table is scoped by tenant, filled with actor or recorded to history
+func auditTable(params *commandParams, opts Options) error {
+	createdAt, updatedAt := opts.Audit.CreatedAt, opts.Audit.UpdatedAt
+	if createdAt == "" {
+		createdAt = "created_at"
+	}
+	if updatedAt == "" {
+		updatedAt = "updated_at"
+	}
+	params.CreatedBy, params.UpdatedBy = nil, nil
+	if params.View {
+		// 视图只读, 没有审计列及变更历史
+		params.History = false
+		params.Context = params.Tenant != nil
+		return nil
+	}
+	for _, f := range params.Fields {
+		f.createdAt = retagAuto(f, f.createdAt, f.column == createdAt, ";autoCreateTime")
+		f.updatedAt = retagAuto(f, f.updatedAt, f.column == updatedAt, ";autoUpdateTime")
+		if opts.Audit.CreatedBy != "" && f.column == opts.Audit.CreatedBy {
+			params.CreatedBy = f
+		}
+		if opts.Audit.UpdatedBy != "" && f.column == opts.Audit.UpdatedBy {
+			params.UpdatedBy = f
+		}
+	}
+	for _, f := range []*field{params.CreatedBy, params.UpdatedBy} {
+		if f == nil {
+			continue
+		}
+		if params.Primary != nil && f == params.Primary.field {
+			return fmt.Errorf("audit column %s is primary key of %s", f.column, params.TableName)
+		}
+		if _, ok := tenantZero[f.Type]; !ok {
+			return fmt.Errorf("unsupported type %s of audit column %s", f.Type, f.column)
+		}
+	}
+	params.History = opts.History
+	params.Context = params.Tenant != nil || params.CreatedBy != nil || params.UpdatedBy != nil || params.History
+	return nil
+}
+
+// retagAuto add or remove gorm auto time tag of field, returns whether the field is auto time
+func retagAuto(f *field, old, auto bool, tag string) bool {
+	switch {
+	case old && !auto:
+		f.Tag = strings.Replace(f.Tag, tag, "", 1)
+	case !old && auto:
+		// 位于comment之前, 与解析时的顺序一致
+		if i := strings.Index(f.Tag, ";comment:"); i >= 0 {
+			f.Tag = f.Tag[:i] + tag + f.Tag[i:]
+		} else {
+			f.Tag += tag
+		}
+	}
+	return auto
+}
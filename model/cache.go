@@ -0,0 +1,203 @@
+// Package model provides ...
+package model
+
+import (
+	"bytes"
+	_ "embed" // embed
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/iancoleman/strcase"
+	"golang.org/x/tools/imports"
+)
+
+//go:embed template/cache.tmpl
+var cacheTmpl string
+
+//go:embed template/cache_model.tmpl
+var cacheModelTmpl string
+
+type cacheTable struct {
+	Name       string // eg. User
+	Table      string // eg. user
+	ObjType    string // eg. model.ObjUser
+	RepoType   string // eg. model.UserRepository
+	UpdateType string // mongodb typed update, eg. model.UpdateUser
+	Import     string
+
+	View    bool // 视图不缓存, 直接使用原repository
+	Primary *field
+	Tenant  *field // 租户列, 缓存key包含context中的租户
+	Context bool   // 方法的第一个参数为context.Context
+	Mongo   bool
+
+	Select []*cacheIndex // 缓存的Select<Table>By<Key>
+	Modify []*cacheIndex // 失效缓存的Delete/Update<Table>By<Key>
+}
+
+type cacheIndex struct {
+	Key    string // eg. EmailAge
+	Input  string // eg. email string, age int
+	Args   string // eg. email, age
+	Values string // 对象的key参数, eg. obj.Email, obj.Age
+	Find   string // 写入前查询对象的表达式
+}
+
+func newCacheIndex(idx index) *cacheIndex {
+	ci := &cacheIndex{}
+	for i, vv := range idx.indexFields {
+		n := strcase.ToLowerCamel(vv.Name)
+		ci.Key += vv.Name
+		if i != 0 {
+			ci.Input += ", "
+			ci.Args += ", "
+			ci.Values += ", "
+		}
+		ci.Input += n + " " + vv.Type
+		ci.Args += n
+		ci.Values += "obj." + vv.Name
+	}
+	return ci
+}
+
+// cacheIndexes collect index of fields by filter, deduplicated by key like dao generators
+func cacheIndexes(params *commandParams, filter func(idx index) bool) []*cacheIndex {
+	var list []*cacheIndex
+	added := make(map[string]bool)
+	for _, f := range params.Fields {
+		for _, idx := range f.indexs {
+			if !filter(idx) {
+				continue
+			}
+			ci := newCacheIndex(idx)
+			if added[ci.Key] {
+				continue
+			}
+			added[ci.Key] = true
+			list = append(list, ci)
+		}
+	}
+	return list
+}
+
+func newCacheTable(dialect Dialect, params *commandParams) *cacheTable {
+	if params.View {
+		return &cacheTable{Name: params.TableName, View: true}
+	}
+	obj := objType(params)
+	ct := &cacheTable{
+		Name:     params.TableName,
+		Table:    strcase.ToSnake(params.TableName),
+		ObjType:  obj,
+		RepoType: strings.Replace(obj, ".Obj", ".", 1) + "Repository",
+		Import:   params.Import,
+		Primary:  params.Primary.field,
+		Tenant:   params.Tenant,
+		Context:  params.Context,
+		Mongo:    dialect == MongoDB,
+	}
+	if ct.Mongo {
+		ct.UpdateType = strings.Replace(obj, ".Obj", ".Update", 1)
+	}
+	ctx := ""
+	if ct.Context {
+		ctx = "ctx, "
+	}
+	// 表达式索引的参数与列值不同, 无法按对象失效, 不缓存
+	unique := func(idx index) bool {
+		if !idx.uniqueIndex || idx.multiColumnExpr {
+			return false
+		}
+		for _, expr := range idx.exprs {
+			if expr != "" {
+				return false
+			}
+		}
+		if ct.Mongo {
+			return !idx.text
+		}
+		return idx.indexName != ""
+	}
+	for _, ci := range cacheIndexes(params, unique) {
+		if ci.Key != ct.Primary.Name {
+			ct.Select = append(ct.Select, ci)
+		}
+	}
+
+	if ct.Mongo {
+		ct.Modify = cacheIndexes(params, func(idx index) bool {
+			return (idx.uniqueIndex || idx.normalIndex) && !idx.text
+		})
+	} else {
+		ct.Modify = cacheIndexes(params, func(idx index) bool {
+			return idx.uniqueIndex && idx.indexName != "" && !idx.multiColumnExpr
+		})
+	}
+	for _, ci := range ct.Modify {
+		ci.Find = fmt.Sprintf("r.%sRepository.Select%sBy%s(%s%s)", ct.Name, ct.Name, ci.Key, ctx, ci.Args)
+	}
+	if ct.Mongo {
+		// 普通索引的Update/Delete只修改第一个匹配的对象
+		selected := make(map[string]bool)
+		for _, ci := range cacheIndexes(params, func(idx index) bool { return idx.uniqueIndex && !idx.text }) {
+			selected[ci.Key] = true
+		}
+		for _, ci := range ct.Modify {
+			if !selected[ci.Key] {
+				ci.Find = fmt.Sprintf("first(r.%sRepository.List%sBy%s(%s%s, 0, 1))", ct.Name, ct.Name, ci.Key, ctx, ci.Args)
+			}
+		}
+	}
+	return ct
+}
+
+// generateCaches generate read-through cache decorators of all tables and cached GlobalModel
+func generateCaches(schema *Schema, opts Options, files map[string][]byte) error {
+	err := ParseTemplate("cacheTmpl", cacheTmpl)
+	if err != nil {
+		return err
+	}
+	err = ParseTemplate("cacheModelTmpl", cacheModelTmpl)
+	if err != nil {
+		return err
+	}
+
+	tables := make([]*cacheTable, len(schema.Tables))
+	for i, t := range schema.Tables {
+		tables[i] = newCacheTable(schema.Dialect, t.params)
+		if t.View {
+			continue
+		}
+		buf := new(bytes.Buffer)
+		err = ExecuteTemplate(buf, "cacheTmpl", map[string]interface{}{
+			"ModelImport": opts.ImportPath,
+			"Table":       tables[i],
+		})
+		if err != nil {
+			return err
+		}
+		data, err := imports.Process("", buf.Bytes(), nil)
+		if err != nil {
+			return err
+		}
+		files[filepath.Join("cache", t.File+".go")] = data
+	}
+
+	buf := new(bytes.Buffer)
+	err = ExecuteTemplate(buf, "cacheModelTmpl", map[string]interface{}{
+		"ModelImport": opts.ImportPath,
+		"Mongo":       schema.Dialect == MongoDB,
+		"List":        tables,
+		"Views":       len(schema.writable()) < len(schema.Tables),
+	})
+	if err != nil {
+		return err
+	}
+	data, err := imports.Process("", buf.Bytes(), nil)
+	if err != nil {
+		return err
+	}
+	files[filepath.Join("cache", "model.go")] = data
+	return nil
+}
@@ -0,0 +1,243 @@
+// Package model provides ...
+package model
+
+import (
+	"bytes"
+	_ "embed" // embed
+	"errors"
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+)
+
+//go:embed template/docs.tmpl
+var docsTmpl string
+
+//go:embed template/docs_mermaid.tmpl
+var docsMermaidTmpl string
+
+//go:embed template/docs_dot.tmpl
+var docsDotTmpl string
+
+// supported ER diagram format of docs
+const (
+	DocsMermaid = "mermaid"
+	DocsDot     = "dot"
+)
+
+// docsCommand generate schema docs by .sql
+var docsCommand = &cli.Command{
+	Name:  "docs",
+	Usage: "to generate schema markdown and ER diagram by .sql",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     "src",
+			Usage:    "DDL file dir, eg. cmd/example/model",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:  "dst",
+			Usage: "Docs dest dir, eg. docs",
+			Value: "docs",
+		},
+		&cli.StringFlag{
+			Name:  "driver",
+			Usage: "DDL file for which DB, mongodb/postgres",
+			Value: "postgres",
+		},
+		&cli.StringFlag{
+			Name:  "format",
+			Usage: "ER diagram format, mermaid(embedded in schema.md)/dot(schema.dot)",
+			Value: DocsMermaid,
+		},
+	},
+	Action: docsAction,
+}
+
+func docsAction(c *cli.Context) error {
+	src := c.String("src")
+	files, err := calculatePath(src, src)
+	if err != nil {
+		return err
+	}
+	schema := &Schema{Dialect: Dialect(c.String("driver"))}
+	for _, file := range files {
+		data, err := os.ReadFile(file.path)
+		if err != nil {
+			return err
+		}
+		sub, err := Parse(bytes.NewReader(data), schema.Dialect)
+		if err != nil {
+			return fmt.Errorf("%s: %w", file.path, err)
+		}
+		schema.Tables = append(schema.Tables, sub.Tables...)
+	}
+
+	docs, err := GenerateDocs(schema, filepath.Base(filepath.Clean(src)), c.String("format"))
+	if err != nil {
+		return err
+	}
+	dst := c.String("dst")
+	_ = os.MkdirAll(dst, 0755)
+	for name, data := range docs {
+		err = os.WriteFile(filepath.Join(dst, name), data, 0644)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type erEntity struct {
+	Name  string
+	Label string // graphviz html label
+	Attrs []*erAttr
+}
+
+type erAttr struct {
+	Name    string
+	Type    string // eg. varchar(1024)
+	Keys    string // eg. PK, FK
+	Comment string
+	Label   string // graphviz html label
+}
+
+type erRelation struct {
+	Parent     string
+	Child      string
+	Card       string // mermaid cardinality, eg. ||--o{
+	Label      string // 外键列名
+	ParentPort string // graphviz port, 未指定引用列时为空
+	ChildPort  string
+}
+
+// newERDiagram entities and relations of schema
+func newERDiagram(schema *Schema) ([]*erEntity, []*erRelation) {
+	typeReplacer := strings.NewReplacer(",", "_", " ", "_")
+	var (
+		entities  []*erEntity
+		relations []*erRelation
+	)
+	for _, t := range schema.Tables {
+		keys := make(map[string][]string)
+		if t.PrimaryKey != nil {
+			keys[t.PrimaryKey.Name] = append(keys[t.PrimaryKey.Name], "PK")
+		}
+		for _, fk := range t.ForeignKeys {
+			for _, v := range fk.Columns {
+				keys[v] = append(keys[v], "FK")
+			}
+		}
+		unique := make(map[string]bool) // 唯一索引的列, 逗号分隔
+		for _, idx := range t.Indexes {
+			if !idx.Unique {
+				continue
+			}
+			unique[strings.Join(idx.Columns, ",")] = true
+			if len(idx.Columns) == 1 {
+				keys[idx.Columns[0]] = append(keys[idx.Columns[0]], "UK")
+			}
+		}
+
+		e := &erEntity{Name: t.Name, Label: html.EscapeString(t.Name)}
+		notNull := make(map[string]bool)
+		for _, c := range t.Columns {
+			notNull[c.Name] = c.NotNull
+			a := &erAttr{
+				Name:    c.Name,
+				Type:    typeReplacer.Replace(strings.ToLower(c.SQLType)),
+				Keys:    strings.Join(keys[c.Name], ", "),
+				Comment: strings.ReplaceAll(c.Comment, `"`, "'"),
+			}
+			a.Label = html.EscapeString(a.Name + " " + a.Type)
+			if a.Keys != "" {
+				a.Label += " <I>" + a.Keys + "</I>"
+			}
+			e.Attrs = append(e.Attrs, a)
+		}
+		entities = append(entities, e)
+
+		for _, fk := range t.ForeignKeys {
+			// 外键列可空时引用方可为0个, 外键列唯一时为1对1
+			parent, child := "||", "o{"
+			for _, v := range fk.Columns {
+				if !notNull[v] {
+					parent = "|o"
+				}
+			}
+			if unique[strings.Join(fk.Columns, ",")] {
+				child = "o|"
+			}
+			r := &erRelation{
+				Parent: fk.RefTable,
+				Child:  t.Name,
+				Card:   parent + "--" + child,
+				Label:  strings.Join(fk.Columns, ", "),
+			}
+			if len(fk.RefColumns) > 0 {
+				r.ParentPort = fk.RefColumns[0]
+			}
+			r.ChildPort = fk.Columns[0]
+			relations = append(relations, r)
+		}
+	}
+	return entities, relations
+}
+
+// GenerateDocs generate markdown of schema with ER diagram, returns file content by name,
+// format is DocsMermaid(embedded in schema.md) or DocsDot(schema.dot)
+func GenerateDocs(schema *Schema, title string, format string) (map[string][]byte, error) {
+	if format != DocsMermaid && format != DocsDot {
+		return nil, errors.New("unsupported docs format: " + format)
+	}
+	for name, text := range map[string]string{
+		"docsTmpl":        docsTmpl,
+		"docsMermaidTmpl": docsMermaidTmpl,
+		"docsDotTmpl":     docsDotTmpl,
+	} {
+		err := ParseTemplate(name, text)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	files := make(map[string][]byte)
+	entities, relations := newERDiagram(schema)
+	diagram := map[string]interface{}{
+		"Title":     title,
+		"Entities":  entities,
+		"Relations": relations,
+	}
+	buf := new(bytes.Buffer)
+	var mermaid string
+	if format == DocsMermaid {
+		err := ExecuteTemplate(buf, "docsMermaidTmpl", diagram)
+		if err != nil {
+			return nil, err
+		}
+		mermaid = buf.String()
+	} else {
+		err := ExecuteTemplate(buf, "docsDotTmpl", diagram)
+		if err != nil {
+			return nil, err
+		}
+		files["schema.dot"] = append([]byte(nil), buf.Bytes()...)
+	}
+
+	buf.Reset()
+	err := ExecuteTemplate(buf, "docsTmpl", map[string]interface{}{
+		"Title":   title,
+		"Dialect": schema.Dialect,
+		"Tables":  schema.Tables,
+		"Mermaid": mermaid,
+	})
+	if err != nil {
+		return nil, err
+	}
+	files["schema.md"] = append([]byte(nil), buf.Bytes()...)
+	return files, nil
+}
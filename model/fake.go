@@ -0,0 +1,307 @@
+// Package model provides ...
+package model
+
+import (
+	"bytes"
+	_ "embed" // embed
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/iancoleman/strcase"
+	"golang.org/x/tools/imports"
+)
+
+//go:embed template/fake.tmpl
+var fakeTmpl string
+
+//go:embed template/fake_model.tmpl
+var fakeModelTmpl string
+
+// repositoryExcluded methods of dao which are not in repository, eg. the mongodb collection
+var repositoryExcluded = map[string]bool{
+	"Collection":     true,
+	"CreateIndexes":  true,
+	"ApplyValidator": true,
+	"Migrate":        true,
+}
+
+// appendRepository append <Table>Repository interface of the exported methods of
+// <Table>Dao to internal file, so that the dao can be replaced by fake in tests
+func appendRepository(src []byte, table string) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	buf := bytes.NewBuffer(append([]byte(nil), src...))
+	buf.WriteString(fmt.Sprintf("\n// %sRepository data access methods of %sDao\n", table, table))
+	buf.WriteString(fmt.Sprintf("type %sRepository interface {\n", table))
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Recv == nil || !fn.Name.IsExported() || repositoryExcluded[fn.Name.Name] {
+			continue
+		}
+		if recv, ok := fn.Recv.List[0].Type.(*ast.Ident); !ok || recv.Name != table+"Dao" {
+			continue
+		}
+		if fn.Doc != nil {
+			for _, c := range fn.Doc.List {
+				buf.WriteString(c.Text + "\n")
+			}
+		}
+		sig := new(bytes.Buffer)
+		err = printer.Fprint(sig, fset, fn.Type)
+		if err != nil {
+			return nil, err
+		}
+		buf.WriteString(fn.Name.Name + strings.TrimPrefix(sig.String(), "func") + "\n")
+	}
+	buf.WriteString("}\n\n")
+	buf.WriteString(fmt.Sprintf("var _ %sRepository = %sDao{}\n", table, table))
+	return imports.Process("", buf.Bytes(), nil)
+}
+
+type fakeTable struct {
+	Name     string // eg. User
+	Mongo    bool
+	Internal string // import path of internal
+
+	View         bool   // 视图只有查询方法, 对象由Put<View>设置
+	Materialized bool   // 生成空的Refresh<View>
+	Concurrently string // 生成空的Refresh<View>Concurrently
+
+	Primary    *field
+	PrimaryPK  string // 主键约束名, eg. user_pkey
+	Tenant     *field // 租户列, 为空时不按租户隔离
+	TenantZero string
+	CreatedBy  *field
+	UpdatedBy  *field
+	History    bool
+	Context    bool // 方法的第一个参数为context.Context
+	Query      []*queryColumn
+	Generate   string // 主键为零值时生成主键的语句
+	CreatedAt  []string
+	UpdatedAt  []string
+	Columns    []*fakeColumn // postgres按列名更新的字段
+	Update     []*fakeColumn // mongodb typed update字段
+
+	Unique []*fakeIndex // 插入及更新时检查的唯一索引
+	Modify []*fakeIndex // Delete/Update<Table>By<Key>
+	Select []*fakeIndex // Select<Table>By<Key>
+	List   []*fakeIndex // List/Count/Exists<Table>By<Key>
+}
+
+type fakeColumn struct {
+	Cases string // eg. "email", "Email"
+	Name  string
+	Type  string
+}
+
+type fakeIndex struct {
+	Name     string // index name
+	Key      string // eg. EmailAge
+	Input    string // eg. email string, age int
+	Args     string // eg. email, age
+	Match    string // 对象v与参数相等的条件
+	Conflict string // 对象v与obj冲突的条件
+}
+
+// fakeEqual equal condition of go type, types are from sqlTypeToGo
+func fakeEqual(a, b, typ string) string {
+	switch typ {
+	case "time.Time":
+		return a + ".Equal(" + b + ")"
+	case "[]byte", "json.RawMessage":
+		return "bytes.Equal(" + a + ", " + b + ")"
+	case "db.StringArray", "db.Int64Array", "[]string", "[]int":
+		return "reflect.DeepEqual(" + a + ", " + b + ")"
+	}
+	return a + " == " + b
+}
+
+func newFakeIndex(idx index) *fakeIndex {
+	fi := &fakeIndex{Name: idx.indexName}
+	var match, conflict []string
+	for i, vv := range idx.indexFields {
+		n := strcase.ToLowerCamel(vv.Name)
+		fi.Key += vv.Name
+		if i != 0 {
+			fi.Input += ", "
+			fi.Args += ", "
+		}
+		fi.Input += n + " " + vv.Type
+		fi.Args += n
+		match = append(match, fakeEqual("v."+vv.Name, n, vv.Type))
+		conflict = append(conflict, fakeEqual("v."+vv.Name, "obj."+vv.Name, vv.Type))
+	}
+	fi.Match = strings.Join(match, " && ")
+	fi.Conflict = strings.Join(conflict, " && ")
+	return fi
+}
+
+// fakeIndexes collect index of fields by filter, deduplicated by key like dao generators
+func fakeIndexes(params *commandParams, filter func(idx index) bool) []*fakeIndex {
+	var list []*fakeIndex
+	added := make(map[string]bool)
+	for _, f := range params.Fields {
+		for _, idx := range f.indexs {
+			if !filter(idx) {
+				continue
+			}
+			fi := newFakeIndex(idx)
+			if added[fi.Key] {
+				continue
+			}
+			added[fi.Key] = true
+			list = append(list, fi)
+		}
+	}
+	return list
+}
+
+func newFakeTable(dialect Dialect, params *commandParams, internal string) *fakeTable {
+	if params.View {
+		ft := &fakeTable{
+			Name:         params.TableName,
+			Internal:     internal,
+			View:         true,
+			Materialized: params.Materialized,
+			Concurrently: params.Concurrently,
+			Tenant:       params.Tenant,
+			Context:      params.Context,
+			Query:        params.QueryColumns,
+		}
+		ft.Select = fakeIndexes(params, func(idx index) bool {
+			return idx.uniqueIndex && idx.indexName != "" && !idx.multiColumnExpr
+		})
+		ft.List = fakeIndexes(params, func(idx index) bool {
+			return idx.normalIndex && !idx.uniqueIndex && idx.indexName != "" && !idx.multiColumnExpr
+		})
+		return ft
+	}
+	ft := &fakeTable{
+		Name:       params.TableName,
+		Mongo:      dialect == MongoDB,
+		Internal:   internal,
+		Primary:    params.Primary.field,
+		PrimaryPK:  strcase.ToSnake(params.TableName) + "_pkey",
+		Tenant:     params.Tenant,
+		TenantZero: params.TenantZero,
+		CreatedBy:  params.CreatedBy,
+		UpdatedBy:  params.UpdatedBy,
+		History:    params.History,
+		Context:    params.Context,
+		Query:      params.QueryColumns,
+	}
+	pk := "obj." + params.Primary.Name
+	switch {
+	case params.Primary.Type == "primitive.ObjectID":
+		ft.Generate = fmt.Sprintf("if %s.IsZero() {\n\t\t%s = primitive.NewObjectID()\n\t}", pk, pk)
+	case params.Primary.Type == "string" && (params.Primary.Autoincrement || params.Primary.ShortID):
+		ft.Generate = fmt.Sprintf("if %s == \"\" {\n\t\tr.seq++\n\t\t%s = strconv.FormatInt(r.seq, 36)\n\t}", pk, pk)
+	case params.Primary.Autoincrement || params.Primary.ShortID:
+		ft.Generate = fmt.Sprintf("if %s == 0 {\n\t\tr.seq++\n\t\t%s = %s(r.seq)\n\t}", pk, pk, params.Primary.Type)
+	}
+	if ft.Mongo {
+		ft.PrimaryPK = "_id_"
+	}
+
+	for _, f := range params.Fields {
+		if !ft.Mongo && f.Type == "time.Time" {
+			if f.createdAt {
+				ft.CreatedAt = append(ft.CreatedAt, f.Name)
+			}
+			if f.updatedAt {
+				ft.UpdatedAt = append(ft.UpdatedAt, f.Name)
+			}
+		}
+		c := &fakeColumn{Cases: fmt.Sprintf("%q", f.column), Name: f.Name, Type: f.Type}
+		if f.column != f.Name {
+			c.Cases += fmt.Sprintf(", %q", f.Name)
+		}
+		if !ft.Mongo {
+			ft.Columns = append(ft.Columns, c)
+		} else if f != params.Primary.field {
+			ft.Update = append(ft.Update, c)
+		}
+	}
+
+	// 部分及稀疏唯一索引的条件不在内存中计算, 不检查
+	added := make(map[string]bool)
+	for _, f := range params.Fields {
+		for _, idx := range f.indexs {
+			if !idx.uniqueIndex || idx.where != "" || idx.sparse || idx.multiColumnExpr || added[idx.indexName] {
+				continue
+			}
+			added[idx.indexName] = true
+			ft.Unique = append(ft.Unique, newFakeIndex(idx))
+		}
+	}
+
+	if ft.Mongo {
+		ft.Modify = fakeIndexes(params, func(idx index) bool {
+			return (idx.uniqueIndex || idx.normalIndex) && !idx.text
+		})
+		ft.Select = fakeIndexes(params, func(idx index) bool { return idx.uniqueIndex && !idx.text })
+		ft.List = fakeIndexes(params, func(idx index) bool { return idx.normalIndex && !idx.text })
+	} else {
+		ft.Modify = fakeIndexes(params, func(idx index) bool {
+			return idx.uniqueIndex && idx.indexName != "" && !idx.multiColumnExpr
+		})
+		ft.Select = ft.Modify
+		ft.List = fakeIndexes(params, func(idx index) bool {
+			return idx.normalIndex && !idx.uniqueIndex && idx.indexName != "" && !idx.multiColumnExpr
+		})
+	}
+	return ft
+}
+
+// generateFakes generate in-memory fake repositories of all tables and fake GlobalModel
+func generateFakes(schema *Schema, opts Options, files map[string][]byte) error {
+	err := ParseTemplate("fakeTmpl", fakeTmpl)
+	if err != nil {
+		return err
+	}
+	err = ParseTemplate("fakeModelTmpl", fakeModelTmpl)
+	if err != nil {
+		return err
+	}
+
+	internal := path.Join(opts.ImportPath, "internal")
+	tables := make([]*fakeTable, len(schema.Tables))
+	for i, t := range schema.Tables {
+		tables[i] = newFakeTable(schema.Dialect, t.params, internal)
+		buf := new(bytes.Buffer)
+		err = ExecuteTemplate(buf, "fakeTmpl", tables[i])
+		if err != nil {
+			return err
+		}
+		data, err := imports.Process("", buf.Bytes(), nil)
+		if err != nil {
+			return err
+		}
+		files[filepath.Join("fake", t.File+".go")] = data
+	}
+
+	buf := new(bytes.Buffer)
+	err = ExecuteTemplate(buf, "fakeModelTmpl", map[string]interface{}{
+		"ModelImport": opts.ImportPath,
+		"Mongo":       schema.Dialect == MongoDB,
+		"List":        tables,
+	})
+	if err != nil {
+		return err
+	}
+	data, err := imports.Process("", buf.Bytes(), nil)
+	if err != nil {
+		return err
+	}
+	files[filepath.Join("fake", "model.go")] = data
+	return nil
+}
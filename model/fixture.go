@@ -0,0 +1,201 @@
+// Package model provides ...
+package model
+
+import (
+	"bytes"
+	_ "embed" // embed
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+
+	"golang.org/x/tools/imports"
+)
+
+//go:embed template/fixture.tmpl
+var fixtureTmpl string
+
+//go:embed template/fixture_seed.tmpl
+var fixtureSeedTmpl string
+
+var regexpTypeLength = regexp.MustCompile(`^(?:VARCHAR|CHARACTER VARYING|CHAR|CHARACTER)\((\d+)\)$`)
+
+type fixtureTable struct {
+	Name    string // eg. User
+	Table   string // eg. user
+	ObjType string // eg. model.ObjUser
+	Import  string
+	Seq     bool // 使用序列值
+	Now     bool // 使用当前时间
+	Values  []*fixtureValue
+
+	Tenant     string // 租户字段名, 为空时不按租户隔离
+	TenantZero string
+	Context    bool // Insert的第一个参数为context.Context
+}
+
+type fixtureValue struct {
+	Name  string
+	Value string // go表达式, n为序列值, eg. fixtureString("email", n, 64)
+}
+
+// fixtureExpr valid value of field and whether the sequence n is used,
+// returns empty if zero value is valid
+func fixtureExpr(f *field) (string, bool) {
+	if len(f.enum) > 0 {
+		return strconv.Quote(f.enum[0]), false
+	}
+	switch f.Type {
+	case "int", "int32":
+		return f.Type + "(n)", true
+	case "int64":
+		return "n", true
+	case "string":
+		max := 0
+		if sub := regexpTypeLength.FindStringSubmatch(f.sqlType); len(sub) == 2 {
+			max, _ = strconv.Atoi(sub[1])
+		}
+		return fmt.Sprintf("fixtureString(%q, n, %d)", f.column, max), true
+	case "[]byte":
+		return fmt.Sprintf("[]byte(fixtureString(%q, n, 0))", f.column), true
+	case "time.Time":
+		return "now", false
+	case "json.RawMessage":
+		return `json.RawMessage("{}")`, false
+	case "db.StringArray":
+		return "db.StringArray{}", false
+	case "db.Int64Array":
+		return "db.Int64Array{}", false
+	case "primitive.ObjectID":
+		return "primitive.NewObjectID()", false
+	}
+	// bool, gorm.DeletedAt等零值有效
+	return "", false
+}
+
+func newFixtureTable(dialect Dialect, t *Table) *fixtureTable {
+	params := t.params
+	ft := &fixtureTable{
+		Name:    params.TableName,
+		Table:   t.Name,
+		ObjType: objType(params),
+		Import:  params.Import,
+		Context: params.Context,
+	}
+	if params.Tenant != nil {
+		ft.Tenant, ft.TenantZero = params.Tenant.Name, params.TenantZero
+	}
+	unique := make(map[*field]bool)
+	for _, f := range params.Fields {
+		for _, idx := range f.indexs {
+			if idx.uniqueIndex {
+				for _, v := range idx.indexFields {
+					unique[v] = true
+				}
+			}
+		}
+	}
+	for _, f := range params.Fields {
+		primary := params.Primary != nil && f == params.Primary.field
+		// 自增及数据库生成的主键, mongodb仅生成ObjectID
+		if primary && (params.Primary.Autoincrement || (params.Primary.ShortID && dialect != MongoDB)) {
+			continue
+		}
+		// 租户由context指定
+		if f == params.Tenant {
+			continue
+		}
+		// NOT NULL, 唯一索引及主键的列
+		if !f.notNull && !unique[f] && !primary {
+			continue
+		}
+		expr, seq := fixtureExpr(f)
+		if expr == "" {
+			continue
+		}
+		ft.Seq = ft.Seq || seq
+		ft.Now = ft.Now || expr == "now"
+		ft.Values = append(ft.Values, &fixtureValue{Name: f.Name, Value: expr})
+	}
+	return ft
+}
+
+// seedOrder tables ordered by foreign keys, the referenced table is seeded first
+func seedOrder(tables []*Table) []*Table {
+	byName := make(map[string]*Table)
+	for _, t := range tables {
+		byName[t.Name] = t
+	}
+	var (
+		list    []*Table
+		visited = make(map[string]bool)
+		visit   func(t *Table)
+	)
+	visit = func(t *Table) {
+		if visited[t.Name] {
+			return
+		}
+		// 循环引用时按定义顺序
+		visited[t.Name] = true
+		for _, fk := range t.ForeignKeys {
+			if ref, ok := byName[fk.RefTable]; ok {
+				visit(ref)
+			}
+		}
+		list = append(list, t)
+	}
+	for _, t := range tables {
+		visit(t)
+	}
+	return list
+}
+
+// generateFixtures generate factories of test objects and seed loader of fixture files
+func generateFixtures(schema *Schema, opts Options, files map[string][]byte) error {
+	err := ParseTemplate("fixtureTmpl", fixtureTmpl)
+	if err != nil {
+		return err
+	}
+	err = ParseTemplate("fixtureSeedTmpl", fixtureSeedTmpl)
+	if err != nil {
+		return err
+	}
+
+	tables := make(map[*Table]*fixtureTable)
+	for _, t := range schema.writable() {
+		ft := newFixtureTable(schema.Dialect, t)
+		tables[t] = ft
+		buf := new(bytes.Buffer)
+		err = ExecuteTemplate(buf, "fixtureTmpl", map[string]interface{}{
+			"ModelImport": opts.ImportPath,
+			"Table":       ft,
+		})
+		if err != nil {
+			return err
+		}
+		data, err := imports.Process("", buf.Bytes(), nil)
+		if err != nil {
+			return err
+		}
+		files[filepath.Join("fixture", t.File+".go")] = data
+	}
+
+	var list []*fixtureTable
+	for _, t := range seedOrder(schema.writable()) {
+		list = append(list, tables[t])
+	}
+	buf := new(bytes.Buffer)
+	err = ExecuteTemplate(buf, "fixtureSeedTmpl", map[string]interface{}{
+		"ModelImport": opts.ImportPath,
+		"List":        list,
+	})
+	if err != nil {
+		return err
+	}
+	data, err := imports.Process("", buf.Bytes(), nil)
+	if err != nil {
+		return err
+	}
+	files[filepath.Join("fixture", "seed.go")] = data
+	return nil
+}
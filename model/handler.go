@@ -0,0 +1,141 @@
+// Package model provides ...
+package model
+
+import (
+	"bytes"
+	_ "embed" // embed
+	"strings"
+
+	"github.com/iancoleman/strcase"
+	"golang.org/x/tools/imports"
+)
+
+//go:embed template/handler.tmpl
+var handlerTmpl string
+
+//go:embed template/router.tmpl
+var routerTmpl string
+
+type handlerGenerator struct {
+	dialect Dialect
+}
+
+type handlerParams struct {
+	*commandParams
+
+	ModelImport    string // GlobalModel所在包
+	ObjType        string // eg. model.ObjUser
+	UpdateType     string // mongodb typed update, eg. model.UpdateUser
+	PrimaryType    string // 路由参数:id的类型
+	NotFound       string // eg. gorm.ErrRecordNotFound
+	NotFoundImport string
+
+	Create []*handlerField
+	Update []*handlerField
+}
+
+type handlerField struct {
+	src *field
+
+	Name     string
+	Type     string
+	JSON     string
+	Column   string
+	Required bool
+	Pointer  bool // 必填的数字为指针, binding:"required"不接受零值
+}
+
+// numericTypes go types of number, 0 of them is rejected by binding:"required"
+var numericTypes = map[string]bool{
+	"int":     true,
+	"int32":   true,
+	"int64":   true,
+	"float32": true,
+	"float64": true,
+}
+
+func newHandlerGenerator(dialect Dialect) (*handlerGenerator, error) {
+	err := ParseTemplate("handlerTmpl", handlerTmpl)
+	if err != nil {
+		return nil, err
+	}
+	err = ParseTemplate("routerTmpl", routerTmpl)
+	if err != nil {
+		return nil, err
+	}
+	return &handlerGenerator{dialect: dialect}, nil
+}
+
+func (h *handlerGenerator) generateHandlerFile(modelImport string, params *commandParams) ([]byte, error) {
+	hp := &handlerParams{
+		commandParams:  params,
+		ModelImport:    modelImport,
+		ObjType:        objType(params),
+		PrimaryType:    params.Primary.Type,
+		NotFound:       "gorm.ErrRecordNotFound",
+		NotFoundImport: "gorm.io/gorm",
+	}
+	if h.dialect == MongoDB {
+		hp.UpdateType = strings.Replace(hp.ObjType, ".Obj", ".Update", 1)
+		hp.NotFound = "mongo.ErrNoDocuments"
+		hp.NotFoundImport = "go.mongodb.org/mongo-driver/mongo"
+	}
+	hp.Create, hp.Update = handlerFields(params)
+
+	buf := new(bytes.Buffer)
+	err := ExecuteTemplate(buf, "handlerTmpl", hp)
+	if err != nil {
+		return nil, err
+	}
+	return imports.Process("", buf.Bytes(), nil)
+}
+
+// objType exported Obj type of custom file, eg. model.ObjUser
+func objType(params *commandParams) string {
+	if params.Import != "" {
+		return params.PkgName + ".Obj" + params.TableName
+	}
+	return "model.Obj" + params.TableName
+}
+
+// handlerFields fields of create and update request
+func handlerFields(params *commandParams) (create, update []*handlerField) {
+	for _, f := range params.Fields {
+		// 租户及操作人由context指定
+		if f.createdAt || f.updatedAt || f.Type == "gorm.DeletedAt" || f == params.Tenant ||
+			f == params.CreatedBy || f == params.UpdatedBy {
+			continue
+		}
+		hf := &handlerField{
+			src:    f,
+			Name:   f.Name,
+			Type:   f.Type,
+			JSON:   strcase.ToSnake(f.Name),
+			Column: f.column,
+		}
+		if params.Primary != nil && f == params.Primary.field {
+			// 自增或数据库生成的主键不可由请求指定
+			if params.Primary.Autoincrement || params.Primary.ShortID {
+				continue
+			}
+		} else {
+			update = append(update, hf)
+		}
+		hf.Required = f.notNull && f.defaultVal == "" && f.Type != "bool"
+		hf.Pointer = hf.Required && numericTypes[f.Type]
+		create = append(create, hf)
+	}
+	return create, update
+}
+
+func (h *handlerGenerator) generateRouterFile(modelImport string, list []*commandParams) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	err := ExecuteTemplate(buf, "routerTmpl", map[string]interface{}{
+		"ModelImport": modelImport,
+		"List":        list,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return imports.Process("", buf.Bytes(), nil)
+}
@@ -0,0 +1,132 @@
+// Package model provides ...
+package model
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Version of generator, change it when generated code changes without template changes
+const Version = "1.0.0"
+
+const (
+	generatedHeader = "// Code generated by zero model. DO NOT EDIT."
+	hashHeader      = "// source hash: "
+)
+
+// sourceHash sha256 of generator version, template, options and DDL of tables
+func sourceHash(opts Options, tmpl string, tables ...*Table) string {
+	opts.Jobs = 0 // 不影响生成的代码
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s\n%+v\n", Version, tmpl, opts)
+	for _, t := range tables {
+		fmt.Fprintf(h, "%s\n%s\n%s\n", t.Dir, t.File, t.Hash)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// stampHash add source hash line after the generated header, data without the header is returned as is
+func stampHash(data []byte, hash string) []byte {
+	header := []byte(generatedHeader + "\n")
+	if !bytes.HasPrefix(data, header) {
+		return data
+	}
+	buf := bytes.NewBuffer(make([]byte, 0, len(data)+len(hashHeader)+len(hash)+1))
+	buf.Write(header)
+	buf.WriteString(hashHeader + hash + "\n")
+	buf.Write(data[len(header):])
+	return buf.Bytes()
+}
+
+// headerHash source hash of generated file, empty if not found
+func headerHash(data []byte) string {
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for i := 0; i < 2 && s.Scan(); i++ {
+		if hash, ok := strings.CutPrefix(s.Text(), hashHeader); ok {
+			return hash
+		}
+	}
+	return ""
+}
+
+// dialectTemplates internal and model template of dialect
+func dialectTemplates(dialect Dialect) (internal, model string) {
+	if dialect == MongoDB {
+		return internalMgoTmpl, modelMgoTmpl
+	}
+	return internalPGTmpl + internalPGViewTmpl, modelPGTmpl
+}
+
+// writeStats counts of files written by writeGenerated
+type writeStats struct {
+	created   int
+	updated   int
+	unchanged int
+	removed   int
+}
+
+func (s writeStats) String() string {
+	return fmt.Sprintf("created: %d, updated: %d, removed: %d, unchanged: %d", s.created, s.updated, s.removed, s.unchanged)
+}
+
+// writeGenerated write generated files to dst, files with the same source hash or content are skipped,
+// custom files are only written if not exist or force
+func writeGenerated(dst string, generated map[string][]byte, custom map[string]bool, force bool) (writeStats, error) {
+	var stats writeStats
+	for name, data := range generated {
+		path := filepath.Join(dst, name)
+		old, err := os.ReadFile(path)
+		switch {
+		case os.IsNotExist(err):
+			stats.created++
+		case err != nil:
+			return stats, err
+		case custom[name] && !force:
+			continue
+		case bytes.Equal(old, data), !force && headerHash(data) != "" && headerHash(old) == headerHash(data):
+			stats.unchanged++
+			continue
+		default:
+			stats.updated++
+		}
+		_ = os.MkdirAll(filepath.Dir(path), 0755)
+		err = os.WriteFile(path, data, 0644)
+		if err != nil {
+			return stats, err
+		}
+	}
+
+	// 删除.sql已删除的internal文件, 只删除带source hash的生成文件
+	list, err := filepath.Glob(filepath.Join(dst, "internal", "*.go"))
+	if err != nil {
+		return stats, err
+	}
+	for _, path := range list {
+		name, err := filepath.Rel(dst, path)
+		if err != nil {
+			return stats, err
+		}
+		if _, ok := generated[name]; ok {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return stats, err
+		}
+		if headerHash(data) == "" {
+			continue
+		}
+		err = os.Remove(path)
+		if err != nil {
+			return stats, err
+		}
+		stats.removed++
+	}
+	return stats, nil
+}
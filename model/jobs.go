@@ -0,0 +1,36 @@
+// Package model provides ...
+package model
+
+import (
+	"errors"
+	"sync"
+)
+
+// runJobs run fn(0)...fn(n-1) by at most jobs goroutines, jobs <= 0 is 1,
+// returns errors of all failed fn in order
+func runJobs(n, jobs int, fn func(i int) error) error {
+	if jobs <= 0 {
+		jobs = 1
+	}
+	if jobs > n {
+		jobs = n
+	}
+	errs := make([]error, n)
+	ch := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < jobs; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range ch {
+				errs[i] = fn(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		ch <- i
+	}
+	close(ch)
+	wg.Wait()
+	return errors.Join(errs...)
+}
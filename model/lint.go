@@ -0,0 +1,296 @@
+// Package model provides ...
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/token"
+	"os"
+	"sort"
+
+	"github.com/iancoleman/strcase"
+	"github.com/urfave/cli/v2"
+)
+
+// rules of Lint
+const (
+	LintInvalidDDL      = "invalid-ddl"          // 无法解析的DDL
+	LintNoPrimaryKey    = "no-primary-key"       // postgres表没有主键
+	LintUnknownColumn   = "unknown-index-column" // 索引引用了不存在的列, 生成时忽略
+	LintForeignIndex    = "foreign-index"        // 索引所在文件不是其表的文件, 生成时忽略
+	LintInlineIndex     = "inline-index"         // 表内的UNIQUE/INDEX, 生成时忽略
+	LintDuplicateIndex  = "duplicate-index"      // 索引名重复
+	LintUnsupportedType = "unsupported-type"     // 列类型无法映射到go类型
+	LintNullableUnique  = "nullable-unique"      // 唯一索引包含可空列
+	LintMissingComment  = "missing-comment"      // 表或列没有COMMENT
+	LintReservedName    = "reserved-name"        // 列名是go关键字或与生成代码的变量冲突
+)
+
+// severity of Issue
+const (
+	SeverityError   = "error"   // 生成失败或生成的代码不符合DDL
+	SeverityWarning = "warning" // 建议修改
+)
+
+// Issue problem of DDL found by Lint
+type Issue struct {
+	File     string `json:"file"`
+	Table    string `json:"table,omitempty"`
+	Column   string `json:"column,omitempty"`
+	Index    string `json:"index,omitempty"`
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+func (i *Issue) String() string {
+	pos := i.File
+	if i.Table != "" {
+		pos += ": " + i.Table
+		if i.Column != "" {
+			pos += "." + i.Column
+		}
+	}
+	return fmt.Sprintf("%s: %s: %s (%s)", pos, i.Severity, i.Message, i.Rule)
+}
+
+var errUnsupportedType = errors.New("unsupported pg type to go")
+
+// generatedNames 生成的DAO方法中的变量, 与索引列的参数名冲突
+var generatedNames = map[string]bool{
+	"ctx":    true,
+	"d":      true,
+	"obj":    true,
+	"fields": true,
+	"offset": true,
+	"limit":  true,
+	"q":      true,
+	"tx":     true,
+	"err":    true,
+}
+
+// lintCommand check .sql without generating
+var lintCommand = &cli.Command{
+	Name:  "lint",
+	Usage: "to check .sql without generating",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     "src",
+			Usage:    "DDL file dir, eg. cmd/example/model",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:  "driver",
+			Usage: "DDL file for which DB, mongodb/postgres",
+			Value: "postgres",
+		},
+		&cli.StringFlag{
+			Name:  "format",
+			Usage: "Output format, text/json",
+			Value: "text",
+		},
+	},
+	Action: lintAction,
+}
+
+func lintAction(c *cli.Context) error {
+	src := c.String("src")
+	list, err := calculatePath(src, src)
+	if err != nil {
+		return err
+	}
+	files := make(map[string][]byte, len(list))
+	for _, file := range list {
+		files[file.path], err = os.ReadFile(file.path)
+		if err != nil {
+			return err
+		}
+	}
+	issues, err := Lint(files, Dialect(c.String("driver")))
+	if err != nil {
+		return err
+	}
+
+	switch c.String("format") {
+	case "json":
+		if issues == nil {
+			issues = []*Issue{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(issues)
+		if err != nil {
+			return err
+		}
+	case "text":
+		for _, v := range issues {
+			fmt.Println(v)
+		}
+	default:
+		return errors.New("unsupported lint format: " + c.String("format"))
+	}
+	n := 0
+	for _, v := range issues {
+		if v.Severity == SeverityError {
+			n++
+		}
+	}
+	if n > 0 {
+		return fmt.Errorf("%d lint errors found", n)
+	}
+	return nil
+}
+
+// Lint check DDL of files by file name without generating, issues are sorted by file
+func Lint(files map[string][]byte, dialect Dialect) ([]*Issue, error) {
+	if dialect != Postgres && dialect != MongoDB {
+		return nil, errors.New("unsupported dialect: " + string(dialect))
+	}
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var (
+		issues  []*Issue
+		list    []*Table
+		tables  = make(map[string]*Table) // 表名 -> 表
+		inFile  = make(map[*Table]string) // 表 -> 文件名
+		defined = make(map[string]string) // 表名 -> 文件名, 含解析失败的表
+	)
+	for _, name := range names {
+		params, err := ddlAnalyzer(files[name])
+		if params != nil {
+			for _, v := range params.issues {
+				v.File = name
+				issues = append(issues, v)
+			}
+			if table := strcase.ToSnake(params.TableName); table != "" && defined[table] == "" {
+				defined[table] = name
+			}
+		}
+		if err != nil {
+			if !errors.Is(err, errUnsupportedType) {
+				issues = append(issues, &Issue{File: name, Rule: LintInvalidDDL, Severity: SeverityError, Message: err.Error()})
+			}
+			continue
+		}
+		if params.TableName == "" {
+			issues = append(issues, &Issue{File: name, Rule: LintInvalidDDL, Severity: SeverityError, Message: "no CREATE TABLE statement found"})
+			continue
+		}
+		// mongodb没有主键时使用_id, 视图没有主键
+		if dialect == Postgres && params.Primary == nil && !params.View {
+			issues = append(issues, &Issue{
+				File:     name,
+				Table:    strcase.ToSnake(params.TableName),
+				Rule:     LintNoPrimaryKey,
+				Severity: SeverityError,
+				Message:  "table has no primary key",
+			})
+		}
+		if dialect == MongoDB {
+			mongoFields(params)
+		}
+		t := newTable(params)
+		if other, ok := tables[t.Name]; ok {
+			issues = append(issues, &Issue{
+				File:     name,
+				Table:    t.Name,
+				Rule:     LintInvalidDDL,
+				Severity: SeverityError,
+				Message:  "table is also defined in " + inFile[other],
+			})
+			continue
+		}
+		list = append(list, t)
+		tables[t.Name], inFile[t] = t, name
+		issues = append(issues, lintTable(t, name)...)
+	}
+
+	// 跨文件检查
+	indexes := make(map[string]*Table)
+	for _, t := range list {
+		for _, idx := range t.Indexes {
+			other, ok := indexes[idx.Name]
+			if !ok {
+				indexes[idx.Name] = t
+				continue
+			}
+			issues = append(issues, &Issue{
+				File:     inFile[t],
+				Table:    t.Name,
+				Index:    idx.Name,
+				Rule:     LintDuplicateIndex,
+				Severity: SeverityError,
+				Message:  fmt.Sprintf("duplicate index name %s, also on table %s", idx.Name, other.Name),
+			})
+		}
+	}
+	for _, v := range issues {
+		if v.Rule != LintForeignIndex {
+			continue
+		}
+		if file, ok := defined[v.Table]; ok {
+			v.Message += ", table is defined in " + file
+		} else {
+			v.Message += ", table is not defined"
+			v.Severity = SeverityError
+		}
+	}
+	sort.SliceStable(issues, func(i, j int) bool {
+		return issues[i].File < issues[j].File
+	})
+	return issues, nil
+}
+
+// lintTable issues of columns and indexes of table
+func lintTable(t *Table, file string) []*Issue {
+	var issues []*Issue
+	add := func(column, index, rule, severity, msg string) {
+		issues = append(issues, &Issue{
+			File:     file,
+			Table:    t.Name,
+			Column:   column,
+			Index:    index,
+			Rule:     rule,
+			Severity: severity,
+			Message:  msg,
+		})
+	}
+
+	if t.Comment == "" {
+		add("", "", LintMissingComment, SeverityWarning, "table has no comment")
+	}
+	columns := make(map[string]*Column, len(t.Columns))
+	for _, c := range t.Columns {
+		columns[c.Name] = c
+		if c.Name == "_id" {
+			continue // mongodb生成的主键
+		}
+		if c.Comment == "" {
+			add(c.Name, "", LintMissingComment, SeverityWarning, "column has no comment")
+		}
+		n := strcase.ToLowerCamel(c.GoName)
+		if token.IsKeyword(n) || generatedNames[n] {
+			add(c.Name, "", LintReservedName, SeverityError,
+				fmt.Sprintf("go name %s of column is a keyword or variable of generated code", n))
+		}
+	}
+	for _, idx := range t.Indexes {
+		if !idx.Unique {
+			continue
+		}
+		for _, name := range idx.Columns {
+			c := columns[name]
+			if c == nil || c.NotNull || c == t.PrimaryKey {
+				continue
+			}
+			add(c.Name, idx.Name, LintNullableUnique, SeverityWarning,
+				fmt.Sprintf("unique index %s has nullable column %s, null values are not unique", idx.Name, c.Name))
+		}
+	}
+	return issues
+}
@@ -2,11 +2,13 @@
 package model
 
 import (
+	"bytes"
 	_ "embed" // embed
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
-	"strings"
+	"runtime"
 
 	"github.com/urfave/cli/v2"
 )
@@ -22,9 +24,8 @@ var ModelCommand = &cli.Command{
 			Usage:   "Force rewrite model file: model/*.go",
 		},
 		&cli.StringFlag{
-			Name:     "src",
-			Usage:    "DDL file dir, eg. cmd/example/model",
-			Required: true,
+			Name:  "src",
+			Usage: "DDL file dir, eg. cmd/example/model",
 		},
 		&cli.StringFlag{
 			Name:  "dst",
@@ -35,25 +36,90 @@ var ModelCommand = &cli.Command{
 			Usage: "DDL file generate for which DB, mongodb/postgres",
 			Value: "postgres",
 		},
+		&cli.BoolFlag{
+			Name:  "handlers",
+			Usage: "Generate gin CRUD handlers: handler/*.go",
+		},
+		&cli.BoolFlag{
+			Name:  "openapi",
+			Usage: "Generate OpenAPI 3 document: openapi.json",
+		},
+		&cli.BoolFlag{
+			Name:  "ts",
+			Usage: "Generate TypeScript types and API client: ts/*.ts",
+		},
+		&cli.BoolFlag{
+			Name:  "proto",
+			Usage: "Generate proto3 messages, gRPC services and converters: proto/*",
+		},
+		&cli.StringFlag{
+			Name:  "validator",
+			Usage: "Apply mongodb $jsonSchema validator in New<Table>Dao, strict/warn",
+		},
+		&cli.StringFlag{
+			Name:  "tenant",
+			Usage: "Tenant column, eg. tenant_id, DAOs of tables with the column are scoped by tenant of context",
+		},
+		&cli.StringFlag{
+			Name:  "created-at",
+			Usage: "Column filled with create time",
+			Value: "created_at",
+		},
+		&cli.StringFlag{
+			Name:  "updated-at",
+			Usage: "Column filled with update time",
+			Value: "updated_at",
+		},
+		&cli.StringFlag{
+			Name:  "created-by",
+			Usage: "Column filled with actor of context on insert, eg. created_by",
+		},
+		&cli.StringFlag{
+			Name:  "updated-by",
+			Usage: "Column filled with actor of context on insert and update, eg. updated_by",
+		},
+		&cli.BoolFlag{
+			Name:  "history",
+			Usage: "Record before/after diffs of updates and deletes to <table>_history",
+		},
+		&cli.BoolFlag{
+			Name:  "fake",
+			Usage: "Generate in-memory fake repositories for tests: fake/*.go",
+		},
+		&cli.BoolFlag{
+			Name:  "fixture",
+			Usage: "Generate test object factories and YAML/JSON seed loader: fixture/*.go",
+		},
+		&cli.BoolFlag{
+			Name:  "cache",
+			Usage: "Generate read-through cache decorators of select methods: cache/*.go",
+		},
+		&cli.BoolFlag{
+			Name:  "docs",
+			Usage: "Generate schema markdown with mermaid ER diagram: docs/schema.md",
+		},
+		&cli.IntFlag{
+			Name:    "jobs",
+			Aliases: []string{"j"},
+			Usage:   "Number of DDL files parsed and generated concurrently",
+			Value:   runtime.NumCPU(),
+		},
 	},
 	Action: commandAction,
+	Subcommands: []*cli.Command{
+		reverseCommand,
+		docsCommand,
+		lintCommand,
+	},
 }
 
 func commandAction(c *cli.Context) (err error) {
 	src := c.String("src")
+	if src == "" {
+		return errors.New(`Required flag "src" not set`)
+	}
 	fmt.Println("sql src: ", src)
 
-	// new generator
-	var generator fileGenerator
-	switch c.String("driver") {
-	case "postgres":
-		generator, err = newPostgresGenerator()
-	case "mongodb":
-		generator, err = newMongoDBGenerator()
-	}
-	if err != nil {
-		return err
-	}
 	dst := c.String("dst")
 	if dst == "" {
 		dst = src
@@ -62,48 +128,73 @@ func commandAction(c *cli.Context) (err error) {
 	if err != nil {
 		return err
 	}
-	// buf
-	list := make([]*commandParams, len(files))
-	for i, file := range files {
-		// internal generate
-		internalDir := filepath.Join(dst, "internal")
-		_ = os.MkdirAll(internalDir, 0755)
-		path := filepath.Join(internalDir, file.name+".go")
-		var data []byte
-		data, err = os.ReadFile(file.path)
-		if err != nil {
-			return err
-		}
-		var params *commandParams
-		params, err = ddlAnalyzer(data)
+	schema := &Schema{Dialect: Dialect(c.String("driver"))}
+	opts := Options{
+		PkgName:    filepath.Base(dst),
+		ImportPath: getPkgName(dst), // 所有文件共用dst, 只执行一次go list -m
+		Handlers:   c.Bool("handlers"),
+		OpenAPI:    c.Bool("openapi"),
+		TypeScript: c.Bool("ts"),
+		Proto:      c.Bool("proto"),
+		Docs:       c.Bool("docs"),
+		Fake:       c.Bool("fake"),
+		Fixture:    c.Bool("fixture"),
+		Cache:      c.Bool("cache"),
+		Validator:  c.String("validator"),
+		Tenant:     c.String("tenant"),
+		Audit: AuditColumns{
+			CreatedAt: c.String("created-at"),
+			UpdatedAt: c.String("updated-at"),
+			CreatedBy: c.String("created-by"),
+			UpdatedBy: c.String("updated-by"),
+		},
+		History: c.Bool("history"),
+		Jobs:    c.Int("jobs"),
+	}
+	// 并发解析, 报告所有文件的错误
+	subs := make([]*Schema, len(files))
+	err = runJobs(len(files), opts.Jobs, func(i int) error {
+		file := files[i]
+		data, err := os.ReadFile(file.path)
 		if err != nil {
 			return err
 		}
-		// internal file
-		err = generator.generateInternalFile(path, params)
+		sub, err := Parse(bytes.NewReader(data), schema.Dialect)
 		if err != nil {
-			return err
+			return fmt.Errorf("%s: %w", file.path, err)
 		}
-
-		// custom generate
-		path = filepath.Join(file.dst, file.name+".go")
-		_, params.PkgName = filepath.Split(file.dst)
-		if _, err = os.Stat(path); os.IsNotExist(err) || c.Bool("f") {
-			params.Import = filepath.Join(file.pkg, "internal")
-			err = generator.generateCustomFile(path, params)
-			if err != nil {
-				return err
+		for _, t := range sub.Tables {
+			t.File = file.name
+			t.Dir, _ = filepath.Rel(dst, file.dst)
+			if t.Dir == "." {
+				t.Dir = ""
 			}
-			params.Import = ""
 		}
-		// model generate
-		if strings.HasPrefix(file.path, file.dst) && file.path != filepath.Join(dst, file.file) {
-			params.Import = filepath.Join(file.pkg, params.PkgName)
-		}
-		list[i] = params
+		subs[i] = sub
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, sub := range subs {
+		schema.Tables = append(schema.Tables, sub.Tables...)
 	}
-	path := filepath.Join(dst, "model.go")
-	return generator.generateModelFile(path, list)
+
+	generated, err := Generate(schema, opts)
+	if err != nil {
+		return err
+	}
+	// custom file只在不存在或--force时生成
+	custom := make(map[string]bool)
+	for _, t := range schema.Tables {
+		custom[t.customFile()] = true
+	}
+	stats, err := writeGenerated(dst, generated, custom, c.Bool("f"))
+	if err != nil {
+		return err
+	}
+	fmt.Println(stats)
+	return nil
 }
 
 type commandParams struct {
@@ -116,9 +207,31 @@ type commandParams struct {
 	IndexGo   string // 索引语句
 
 	MgoIndex string // mongodb index
+	Comment  string // COMMENT ON TABLE
+
+	Tenant     *field // 租户列, 为空时不按租户隔离
+	TenantZero string // 租户列类型的零值, eg. 0
+	CreatedBy  *field // 创建人列, 由context中的操作人填充
+	UpdatedBy  *field // 更新人列, 由context中的操作人填充
+	History    bool   // 记录Update/Delete的变更到<table>_history
+	Context    bool   // DAO方法的第一个参数为context.Context
+
+	QueryVar     string         // 类型化列的变量名, eg. Users
+	QueryColumns []*queryColumn // 类型化列
+
+	View         bool   // CREATE VIEW, 只生成查询方法
+	Materialized bool   // CREATE MATERIALIZED VIEW, 生成Refresh方法
+	Concurrently string // 可用于REFRESH CONCURRENTLY的唯一索引, 为空时不生成
+
+	enums       map[string][]string // CREATE TYPE ... AS ENUM
+	foreignKeys []foreignKey
+	issues      []*Issue // 解析时发现的问题, 由lint报告
 }
 
 type field struct {
+	column     string // DDL列名
+	sqlType    string
+	enum       []string // 枚举值
 	defaultVal string
 	notNull    bool
 	indexs     []index
@@ -128,7 +241,8 @@ type field struct {
 	Name    string // 字段名称
 	Type    string // 数据类型
 	Tag     string // tag
-	Comment string // 备注
+	Bson    string // mongodb bson tag
+	Comment string // COMMENT ON COLUMN
 }
 
 type index struct {
@@ -136,6 +250,24 @@ type index struct {
 	normalIndex bool
 	indexFields []*field
 	indexName   string
+
+	exprs              []string // 与indexFields对应, 表达式索引的表达式, eg. lower(email)
+	multiColumnExpr    bool     // 表达式引用了多个列, 不生成DAO方法
+	sorts              []string // 与indexFields对应, eg. DESC NULLS LAST
+	using              string   // 索引类型, eg. gin
+	concurrently       bool
+	include            []string // INCLUDE的列名
+	where              string   // 部分索引条件
+	sparse             bool     // mongodb sparse
+	text               bool     // mongodb text
+	ttl                bool     // mongodb TTL
+	expireAfterSeconds int32
+}
+
+type foreignKey struct {
+	fields     []*field
+	refTable   string   // DDL表名
+	refColumns []string // 未指定时为空, 即引用表的主键
 }
 
 type primaryKey struct {
@@ -146,7 +278,7 @@ type primaryKey struct {
 }
 
 type fileGenerator interface {
-	generateInternalFile(path string, params *commandParams) error
-	generateCustomFile(path string, params *commandParams) error
-	generateModelFile(path string, list []*commandParams) error
+	generateInternalFile(params *commandParams) ([]byte, error)
+	generateCustomFile(params *commandParams) ([]byte, error)
+	generateModelFile(list []*commandParams) ([]byte, error)
 }
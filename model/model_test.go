@@ -2,12 +2,33 @@
 package model
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"go/types"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
 	"testing"
 
+	"github.com/iancoleman/strcase"
 	"github.com/urfave/cli/v2"
+	"golang.org/x/tools/imports"
 )
 
+var update = flag.Bool("update", false, "update golden files of testdata/golden")
+
 var ddlSQLs = []string{
 	`CREATE TABLE "user" (
 	    id         SERIAL                              NOT NULL,
@@ -66,6 +87,10 @@ COMMENT ON COLUMN source.updated_at IS '修改时间';
 COMMENT ON COLUMN source.delete_at IS '删除时间';`,
 }
 
+// userDDL ddlSQLs[0] with the unique index, in-table UNIQUE is ignored
+var userDDL = ddlSQLs[0] + `
+CREATE UNIQUE INDEX idx_user_email_age ON "user" (email, age);`
+
 func TestDDLParse(t *testing.T) {
 	for _, v := range ddlSQLs {
 		params, err := ddlAnalyzer([]byte(v))
@@ -83,8 +108,1556 @@ func TestCommandModel(t *testing.T) {
 		ModelCommand,
 	}
 
-	err := app.Run([]string{"zero", "model", "--src", "testdata"})
+	dst := filepath.Join(t.TempDir(), "model")
+	err := app.Run([]string{"zero", "model", "--src", "testdata", "--dst", dst})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"model.go", "user.go", "internal/user.go", "internal/fido_credential.go"} {
+		if _, err = os.Stat(filepath.Join(dst, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	f, err := os.Open("testdata/fido_credential.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	schema, err := Parse(f, Postgres)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(schema.Tables) != 1 {
+		t.Fatalf("tables: %d", len(schema.Tables))
+	}
+	table := schema.Tables[0]
+	if table.Name != "fido_credential" || table.GoName != "FidoCredential" || table.Comment != "凭证表" {
+		t.Fatalf("table: %s %s %s", table.Name, table.GoName, table.Comment)
+	}
+	if table.PrimaryKey == nil || table.PrimaryKey.Name != "id" {
+		t.Fatal("primary key not parsed")
+	}
+	if len(table.Columns) != 9 {
+		t.Fatalf("columns: %d", len(table.Columns))
+	}
+	tenant := table.Columns[1]
+	if tenant.Name != "tenant_id" || tenant.GoName != "TenantId" || tenant.GoType != "int" ||
+		!tenant.NotNull || tenant.Comment != "租户ID" {
+		t.Fatalf("column: %+v", tenant)
+	}
+	if len(table.Indexes) != 2 {
+		t.Fatalf("indexes: %d", len(table.Indexes))
+	}
+	for _, idx := range table.Indexes {
+		if idx.Name == "idx_fido_credential_credential_id" && (!idx.Unique || idx.Columns[0] != "credential_id") {
+			t.Fatalf("index: %+v", idx)
+		}
+	}
+}
+
+func TestGenerateComment(t *testing.T) {
+	raw, err := os.ReadFile("testdata/fido_credential.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, dialect := range []Dialect{Postgres, MongoDB} {
+		schema, err := Parse(bytes.NewReader(raw), dialect)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files, err := Generate(schema, Options{ImportPath: "example.com/app/model"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		internal := string(files[filepath.Join("internal", "fido_credential.go")])
+		if !strings.Contains(internal, "// FidoCredentialObj 凭证表\ntype FidoCredentialObj struct {") ||
+			!strings.Contains(internal, "\t// TenantId 租户ID\n\tTenantId int ") {
+			t.Fatalf("%s: internal:\n%s", dialect, internal)
+		}
+		if !strings.Contains(string(files["fido_credential.go"]), "// ObjFidoCredential 凭证表\n") {
+			t.Fatalf("%s: custom:\n%s", dialect, files["fido_credential.go"])
+		}
+	}
+}
+
+func TestParseIndex(t *testing.T) {
+	schema, err := Parse(strings.NewReader(`CREATE TABLE "account" (
+	    id         SERIAL    NOT NULL,
+	    email      TEXT      NOT NULL,
+	    tags       TEXT[],
+	    score      INTEGER   NOT NULL,
+	    name       TEXT,
+	    deleted_at TIMESTAMP,
+	    PRIMARY KEY (id)
+	);
+	CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS idx_account_email ON account (lower(email))
+	    WHERE deleted_at IS NULL;
+	CREATE INDEX idx_account_tags ON public.account USING GIN (tags);
+	CREATE INDEX idx_account_score ON account (score DESC NULLS LAST, name) INCLUDE (email);
+	CREATE INDEX ON account (name, lower(name || email));`), Postgres)
+	if err != nil {
+		t.Fatal(err)
+	}
+	table := schema.Tables[0]
+	want := []*Index{
+		{Name: "idx_account_email", Columns: []string{"email"}, Expressions: []string{"lower(email)"}, Unique: true, Where: "deleted_at IS NULL"},
+		{Name: "idx_account_tags", Columns: []string{"tags"}, Using: "gin"},
+		{Name: "idx_account_score", Columns: []string{"score", "name"}, Include: []string{"email"}},
+		{Name: "idx_account_name_name", Columns: []string{"name", "name"}, Expressions: []string{"", "lower(name || email)"}},
+	}
+	if !reflect.DeepEqual(table.Indexes, want) {
+		for _, v := range table.Indexes {
+			t.Logf("%+v", v)
+		}
+		t.Fatal("indexes mismatch")
+	}
+	tags := map[string]string{
+		"email": "column:email;not null;uniqueIndex:idx_account_email,option:CONCURRENTLY,where:deleted_at IS NULL,expression:lower(email)",
+		"tags":  "column:tags;index:idx_account_tags,type:gin",
+		"score": "column:score;not null;index:idx_account_score,sort:desc nulls last,priority:1",
+	}
+	for _, c := range table.Columns {
+		if tag, ok := tags[c.Name]; ok && c.Tag != tag {
+			t.Fatalf("tag of %s: %s", c.Name, c.Tag)
+		}
+	}
+
+	files, err := Generate(schema, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	internal := string(files[filepath.Join("internal", "account.go")])
+	if !strings.Contains(internal, `func (d AccountDao) SelectAccountByEmail(email string) (*AccountObj, error) {`) ||
+		!strings.Contains(internal, `d.DB.Where("lower(email)=lower(?) AND (deleted_at IS NULL)", email)`) {
+		t.Fatalf("dao of partial expression index:\n%s", internal)
+	}
+	for _, v := range []string{
+		`func (d AccountDao) ListAccountByScoreName(score int, name string, offset, limit int) ([]*AccountObj, error) {`,
+		`func (d AccountDao) CountAccountByScoreName(score int, name string) (int64, error) {`,
+		`func (d AccountDao) ExistsAccountByScoreName(score int, name string) (bool, error) {`,
+		`func (d AccountDao) ListAccountByTags(tags db.StringArray, offset, limit int) ([]*AccountObj, error) {`,
+	} {
+		if !strings.Contains(internal, v) {
+			t.Fatalf("%q not found:\n%s", v, internal)
+		}
+	}
+	if strings.Contains(internal, "ListAccountByNameName") {
+		t.Fatalf("dao of multi column expression index:\n%s", internal)
+	}
+}
+
+func TestParseEnum(t *testing.T) {
+	schema, err := Parse(strings.NewReader(`CREATE TYPE user_status AS ENUM ('active', 'disabled');
+	CREATE TABLE "user" (
+	    id     SERIAL      NOT NULL,
+	    status user_status NOT NULL,
+	    kind   TEXT        CHECK (kind IN ('a', 'it''s')),
+	    PRIMARY KEY (id)
+	);`), Postgres)
+	if err != nil {
+		t.Fatal(err)
+	}
+	columns := schema.Tables[0].Columns
+	if columns[1].GoType != "string" || !reflect.DeepEqual(columns[1].Enum, []string{"active", "disabled"}) {
+		t.Fatalf("column: %+v", columns[1])
+	}
+	if !reflect.DeepEqual(columns[2].Enum, []string{"a", "it's"}) {
+		t.Fatalf("column: %+v", columns[2])
+	}
+}
+
+func TestGenerateSchemaByHand(t *testing.T) {
+	for _, dialect := range []Dialect{Postgres, MongoDB} {
+		parsed, err := Parse(strings.NewReader(userDDL), dialect)
+		if err != nil {
+			t.Fatal(err)
+		}
+		opts := Options{ImportPath: "example.com/app/model", Handlers: true, Proto: true, Fake: true, Cache: true}
+		want, err := Generate(parsed, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// 生成不修改schema, 其他选项不影响再次生成
+		_, err = Generate(parsed, Options{PkgName: "other", ImportPath: "example.com/other", Tenant: "age", History: true, Fake: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := Generate(parsed, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("%s: output of Generate changed by previous Generate", dialect)
+		}
+
+		// 只有导出字段的schema
+		byHand := &Schema{Dialect: dialect}
+		for _, v := range parsed.Tables {
+			table := *v
+			table.params = nil
+			byHand.Tables = append(byHand.Tables, &table)
+		}
+		got, err = Generate(byHand, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for name, data := range want {
+			if !bytes.Equal(got[name], data) {
+				t.Fatalf("%s: %s of schema by hand:\n%s\nwant:\n%s", dialect, name, got[name], data)
+			}
+		}
+
+		// 调整导出字段
+		byHand.Tables[0].Comment = "user of app"
+		byHand.Tables[0].Columns[1].Comment = "nick name"
+		got, err = Generate(byHand, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		internal := string(got[filepath.Join("internal", "user.go")])
+		if !strings.Contains(internal, "user of app") || !strings.Contains(internal, "nick name") {
+			t.Fatalf("%s: adjusted comment not generated:\n%s", dialect, internal)
+		}
+		byHand.Tables[0].Indexes[0].Columns = []string{"unknown"}
+		if _, err = Generate(byHand, opts); err == nil || !strings.Contains(err.Error(), "unknown column unknown") {
+			t.Fatalf("%s: index of unknown column: %v", dialect, err)
+		}
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	for _, dialect := range []Dialect{Postgres, MongoDB} {
+		schema, err := Parse(strings.NewReader(userDDL), dialect)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files, err := Generate(schema, Options{
+			ImportPath: "example.com/app/model",
+			Handlers:   true,
+			OpenAPI:    true,
+			TypeScript: true,
+			Proto:      true,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		internal := string(files[filepath.Join("internal", "user.go")])
+		if !strings.Contains(internal, "func (d UserDao) SelectUserByEmailAge(email string, age int) (*UserObj, error)") {
+			t.Fatalf("%s: unique index dao not generated:\n%s", dialect, internal)
+		}
+		if !strings.Contains(string(files["user.go"]), `"example.com/app/model/internal"`) {
+			t.Fatalf("%s: custom import:\n%s", dialect, files["user.go"])
+		}
+		if _, ok := files["model.go"]; !ok {
+			t.Fatalf("%s: model.go not generated", dialect)
+		}
+		handler := string(files[filepath.Join("handler", "user.go")])
+		if !strings.Contains(handler, "func (h UserHandler) CreateUser(c *gin.Context)") ||
+			!strings.Contains(handler, "Email string `json:\"email\" binding:\"required\"`") {
+			t.Fatalf("%s: handler:\n%s", dialect, handler)
+		}
+		// 更新/删除不存在的对象与查询一样返回ErrNotFound
+		notFound := map[Dialect]string{Postgres: "gorm.ErrRecordNotFound", MongoDB: "mongo.ErrNoDocuments"}[dialect]
+		for _, method := range []string{"SelectUser", "UpdateUser", "DeleteUser"} {
+			_, body, _ := strings.Cut(handler, "func (h UserHandler) "+method+"(")
+			body, _, _ = strings.Cut(body, "\n}\n")
+			if !strings.Contains(body, "if errors.Is(err, "+notFound+") {\n\t\tginhelper.StopExec(ErrNotFound)") ||
+				strings.Count(body, "h.Model.") != 1 {
+				t.Fatalf("%s: not found of %s:\n%s", dialect, method, handler)
+			}
+		}
+		// 必填的数字为指针, 0是合法的值
+		if !strings.Contains(handler, "Age   *int   `json:\"age\" binding:\"required\"`") || !strings.Contains(handler, "Age:   *req.Age,") {
+			t.Fatalf("%s: required number of handler:\n%s", dialect, handler)
+		}
+		if !strings.Contains(string(files[filepath.Join("handler", "router.go")]), `UserHandler{Model: m}.Register(r.Group("/user"))`) {
+			t.Fatalf("%s: router:\n%s", dialect, files[filepath.Join("handler", "router.go")])
+		}
+		var doc struct {
+			Paths      map[string]interface{}
+			Components struct {
+				Schemas map[string]struct {
+					Required []string
+				}
+			}
+		}
+		err = json.Unmarshal(files["openapi.json"], &doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if doc.Paths["/user/{id}"] == nil || len(doc.Components.Schemas["User"].Required) != 5 {
+			t.Fatalf("%s: openapi:\n%s", dialect, files["openapi.json"])
+		}
+		ts := string(files[filepath.Join("ts", "model.ts")])
+		if !strings.Contains(ts, "export interface User {") || !strings.Contains(ts, "  created_at: string;") {
+			t.Fatalf("%s: ts:\n%s", dialect, ts)
+		}
+		if !strings.Contains(string(files[filepath.Join("ts", "client.ts")]), "createUser(req: CreateUserReq): Promise<User>") {
+			t.Fatalf("%s: ts client:\n%s", dialect, files[filepath.Join("ts", "client.ts")])
+		}
+		proto := string(files[filepath.Join("proto", "model.proto")])
+		if !strings.Contains(proto, "rpc SelectUserByEmailAge(UserByEmailAge) returns (User);") ||
+			!strings.Contains(proto, "google.protobuf.Timestamp created_at = 5;") {
+			t.Fatalf("%s: proto:\n%s", dialect, proto)
+		}
+		if !strings.Contains(string(files[filepath.Join("proto", "user_convert.go")]), "CreatedAt: timeToProto(obj.CreatedAt),") {
+			t.Fatalf("%s: proto converter:\n%s", dialect, files[filepath.Join("proto", "user_convert.go")])
+		}
+	}
+}
+
+func TestGenerateTypeScriptBigint(t *testing.T) {
+	schema, err := Parse(strings.NewReader(`CREATE TABLE counter (
+	    id    SERIAL NOT NULL,
+	    total BIGINT NOT NULL,
+	    PRIMARY KEY (id)
+	);
+	COMMENT ON TABLE counter IS 'see /* note */';
+	COMMENT ON COLUMN counter.total IS 'above 2^53 */ alert(1)';`), Postgres)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := Generate(schema, Options{ImportPath: "example.com/app/model", Handlers: true, OpenAPI: true, TypeScript: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// int64以字符串传输, 避免javascript丢失精度
+	for name, v := range map[string]string{
+		filepath.Join("internal", "counter.go"): "`gorm:\"column:total;not null;comment:above 2^53 */ alert(1)\" json:\"total,string\"`",
+		filepath.Join("handler", "counter.go"):  "Total *int64 `json:\"total,string\" binding:\"required\"`",
+		filepath.Join("ts", "model.ts"):         "  /** above 2^53 *\\/ alert(1) */\n  total: string;",
+		"openapi.json":                          `"total": { "type": "string", "format": "int64", "description": "above 2^53 */ alert(1)" }`,
+	} {
+		data := string(files[name])
+		if name == "openapi.json" {
+			data = regexp.MustCompile(`\s+`).ReplaceAllString(data, " ")
+			v = regexp.MustCompile(`\s+`).ReplaceAllString(v, " ")
+		}
+		if !strings.Contains(data, v) {
+			t.Fatalf("%q not found in %s:\n%s", v, name, data)
+		}
+	}
+	if ts := string(files[filepath.Join("ts", "model.ts")]); !strings.Contains(ts, "/** see /* note *\\/ */") {
+		t.Fatalf("table comment of ts:\n%s", ts)
+	}
+	if client := string(files[filepath.Join("ts", "client.ts")]); !strings.Contains(client, "throw new ApiError(resp.status, ") {
+		t.Fatalf("non-JSON response of ts client:\n%s", client)
+	}
+}
+
+func TestGenerateMongo(t *testing.T) {
+	schema, err := Parse(strings.NewReader(ddlSQLs[2]), MongoDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := Generate(schema, Options{ImportPath: "example.com/app/model"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	internal := string(files[filepath.Join("internal", "source.go")])
+	for _, v := range []string{
+		"ID primitive.ObjectID `bson:\"_id,omitempty\" json:\"id\"`",
+		"Platform int `bson:\"platform,omitempty\" json:\"platform\"`",
+		"Url       *string    `bson:\"url,omitempty\" json:\"url,omitempty\"`",
+		"func (d SourceDao) UpdateSource(id primitive.ObjectID, update *SourceUpdate) error {",
+		"obj.ID = id",
+	} {
+		if !strings.Contains(internal, v) {
+			t.Fatalf("%q not found:\n%s", v, internal)
+		}
+	}
+	if !strings.Contains(string(files["source.go"]), "type UpdateSource = internal.SourceUpdate") {
+		t.Fatalf("custom:\n%s", files["source.go"])
+	}
+}
+
+func TestGenerateMongoValidator(t *testing.T) {
+	schema, err := Parse(strings.NewReader(`CREATE TYPE user_status AS ENUM ('active', 'disabled');
+	CREATE TABLE "user" (
+	    id     SERIAL      NOT NULL,
+	    name   TEXT        NOT NULL,
+	    status user_status,
+	    PRIMARY KEY (id)
+	);
+	COMMENT ON COLUMN user.name IS '名称';`), MongoDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := Generate(schema, Options{Validator: ValidatorWarn})
+	if err != nil {
+		t.Fatal(err)
+	}
+	internal := string(files[filepath.Join("internal", "user.go")])
+	for _, v := range []string{
+		`"required": bson.A{"_id", "name"},`,
+		`"name":   bson.M{"bsonType": "string", "description": "名称"},`,
+		`"status": bson.M{"bsonType": bson.A{"string", "null"}, "enum": bson.A{"active", "disabled", nil}},`,
+		`SetValidationLevel("strict").SetValidationAction("warn")`,
+		"err := d.ApplyValidator(ctx)\n\tif err != nil {\n\t\treturn err\n\t}\n\treturn d.CreateIndexes(ctx)",
+	} {
+		if !strings.Contains(internal, v) {
+			t.Fatalf("%q not found:\n%s", v, internal)
+		}
+	}
+	// 构造DAO不访问数据库, 由Migrate返回错误
+	if !strings.Contains(internal, "func NewUserDao(ormDB *mongo.Database) UserDao {\n\treturn UserDao{DB: ormDB}\n}") ||
+		!strings.Contains(string(files["model.go"]), "if err := NewUser(ormDB).Migrate(ctx); err != nil {") {
+		t.Fatalf("dao constructor with side effect:\n%s\n%s", internal, files["model.go"])
+	}
+
+	files, err = Generate(schema, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(files[filepath.Join("internal", "user.go")]), "ApplyValidator") {
+		t.Fatal("validator generated without option")
+	}
+	if _, err = Generate(schema, Options{Validator: "moderate"}); err == nil {
+		t.Fatal("unsupported validator should fail")
+	}
+}
+
+func TestGenerateMongoIndex(t *testing.T) {
+	schema, err := Parse(strings.NewReader(`CREATE TABLE session (
+	    id         TEXT      NOT NULL,
+	    user_id    INTEGER   NOT NULL,
+	    token      TEXT,
+	    content    TEXT,
+	    created_at TIMESTAMP NOT NULL,
+	    PRIMARY KEY (id)
+	);
+	-- mongo: expireAfterSeconds=3600
+	CREATE INDEX idx_session_created_at ON session (created_at);
+	-- mongo: sparse
+	CREATE UNIQUE INDEX idx_session_token ON session (token);
+	-- mongo: text
+	CREATE INDEX idx_session_content ON session (content);
+	CREATE INDEX idx_session_user_id ON session (user_id DESC, created_at)
+	    WHERE user_id > 0 AND token IS NOT NULL;`), MongoDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := Generate(schema, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	internal := string(files[filepath.Join("internal", "session.go")])
+	for _, v := range []string{
+		`Options: options.Index().SetName("idx_session_created_at").SetExpireAfterSeconds(3600),`,
+		`Options: options.Index().SetName("idx_session_token").SetUnique(true).SetSparse(true),`,
+		`Keys:    bson.D{{Key: "content", Value: "text"}},`,
+		`Keys:    bson.D{{Key: "user_id", Value: -1}, {Key: "created_at", Value: 1}},`,
+		`SetPartialFilterExpression(bson.D{{Key: "user_id", Value: bson.M{"$gt": 0}}, {Key: "token", Value: bson.M{"$exists": true}}}),`,
+		`return fmt.Errorf("create indexes of session: %w", err)`,
+	} {
+		if !strings.Contains(internal, v) {
+			t.Fatalf("%q not found:\n%s", v, internal)
+		}
+	}
+	if !strings.Contains(internal, `func (d SessionDao) ListSessionByUserIdCreatedAt(userId int, createdAt time.Time, offset, limit int) ([]*SessionObj, error) {`) ||
+		!strings.Contains(internal, `func (d SessionDao) ExistsSessionByUserIdCreatedAt(userId int, createdAt time.Time) (bool, error) {`) ||
+		strings.Contains(internal, "SelectSessionByUserIdCreatedAt") {
+		t.Fatalf("dao of normal index:\n%s", internal)
+	}
+	if strings.Contains(internal, "SelectSessionByContent") || strings.Contains(internal, "ListSessionByContent") {
+		t.Fatalf("dao of text index:\n%s", internal)
+	}
+
+	_, err = Parse(strings.NewReader(`CREATE TABLE session (
+	    id TEXT NOT NULL,
+	    PRIMARY KEY (id)
+	);
+	-- mongo: unknown
+	CREATE INDEX idx_session_id ON session (id);`), MongoDB)
+	if err == nil {
+		t.Fatal("unknown index option should fail")
+	}
+}
+
+func TestReverse(t *testing.T) {
+	raw, err := os.ReadFile("testdata/fido_credential.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema, err := Parse(bytes.NewReader(raw), Postgres)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := Generate(schema, Options{ImportPath: "example.com/app/model"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	err = os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/app\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(dir, "fido_credential.go"), files[filepath.Join("internal", "fido_credential.go")], 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ddl, err := Reverse(dir, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	reversed, err := Parse(bytes.NewReader(ddl["fido_credential.sql"]), Postgres)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, got := schema.Tables[0], reversed.Tables[0]
+	if want.Name != got.Name || len(want.Columns) != len(got.Columns) || len(want.Indexes) != len(got.Indexes) {
+		t.Fatalf("table mismatch:\n%s", ddl["fido_credential.sql"])
+	}
+	for i, v := range want.Columns {
+		if !reflect.DeepEqual(v, got.Columns[i]) {
+			t.Fatalf("column mismatch:\n%+v\n%+v", v, got.Columns[i])
+		}
+	}
+	for i, v := range want.Indexes {
+		if !reflect.DeepEqual(v, got.Indexes[i]) {
+			t.Fatalf("index mismatch:\n%+v\n%+v", v, got.Indexes[i])
+		}
+	}
+	if got.PrimaryKey == nil || got.PrimaryKey.Name != want.PrimaryKey.Name {
+		t.Fatal("primary key mismatch")
+	}
+}
+
+func TestGenerateDocs(t *testing.T) {
+	schema := &Schema{Dialect: Postgres}
+	for _, name := range []string{"user.sql", "fido_credential.sql", "user_login.sql"} {
+		raw, err := os.ReadFile(filepath.Join("testdata", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		sub, err := Parse(bytes.NewReader(raw), Postgres)
+		if err != nil {
+			t.Fatal(err)
+		}
+		schema.Tables = append(schema.Tables, sub.Tables...)
+	}
+	want := []*ForeignKey{
+		{Columns: []string{"user_id"}, RefTable: "user", RefColumns: []string{"id"}},
+		{Columns: []string{"fido_credential_id"}, RefTable: "fido_credential", RefColumns: []string{"id"}},
+	}
+	if got := schema.Tables[2].ForeignKeys; !reflect.DeepEqual(got, want) {
+		t.Fatalf("foreign keys: %+v", got)
+	}
+
+	docs, err := GenerateDocs(schema, "model", DocsMermaid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	md := string(docs["schema.md"])
+	for _, v := range []string{
+		"```mermaid\nerDiagram\n",
+		"        serial id PK \"自增ID\"\n",
+		"    user ||--o{ user_login : \"user_id\"\n",
+		"    fido_credential |o--o{ user_login : \"fido_credential_id\"\n",
+		"| credential_id | TEXT | string | NO |  | 凭证ID |\n",
+		"| idx_fido_credential_credential_id | credential_id | YES |\n",
+		"| fido_credential_id | [fido_credential](#fido_credential) (id) |\n",
+	} {
+		if !strings.Contains(md, v) {
+			t.Fatalf("%q not found in markdown:\n%s", v, md)
+		}
+	}
+
+	docs, err = GenerateDocs(schema, "model", DocsDot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(docs["schema.dot"]), `"user":"id" -> "user_login":"user_id" [label="user_id"];`) ||
+		strings.Contains(string(docs["schema.md"]), "```mermaid") {
+		t.Fatalf("dot:\n%s\n%s", docs["schema.dot"], docs["schema.md"])
+	}
+}
+
+// generateTestdata generate all files of testdata/*.sql with all options
+func generateTestdata(t *testing.T, dialect Dialect) map[string][]byte {
+	names, err := filepath.Glob(filepath.Join("testdata", "*.sql"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema := &Schema{Dialect: dialect}
+	for _, name := range names {
+		raw, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sub, err := Parse(bytes.NewReader(raw), dialect)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sub.Tables[0].File = strings.TrimSuffix(filepath.Base(name), ".sql")
+		schema.Tables = append(schema.Tables, sub.Tables...)
+	}
+	opts := Options{
+		ImportPath: "example.com/app/model",
+		Handlers:   true,
+		OpenAPI:    true,
+		TypeScript: true,
+		Proto:      true,
+		Docs:       true,
+		Fake:       true,
+		Fixture:    true,
+		Cache:      true,
+		Tenant:     "tenant_id",
+		Jobs:       4,
+	}
+	if dialect == MongoDB {
+		opts.Validator = ValidatorStrict
+	}
+	files, err := Generate(schema, opts)
 	if err != nil {
 		t.Fatal(err)
 	}
+	return files
+}
+
+// TestGolden compare generated files with testdata/golden/<dialect>/*.golden,
+// run `go test -run TestGolden -update` to update
+func TestGolden(t *testing.T) {
+	for _, dialect := range []Dialect{Postgres, MongoDB} {
+		files := generateTestdata(t, dialect)
+		for i := 0; i < 3; i++ {
+			if !reflect.DeepEqual(files, generateTestdata(t, dialect)) {
+				t.Fatalf("%s: output is not deterministic", dialect)
+			}
+		}
+
+		dir := filepath.Join("testdata", "golden", string(dialect))
+		if *update {
+			err := os.RemoveAll(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for name, data := range files {
+				path := filepath.Join(dir, name+".golden")
+				_ = os.MkdirAll(filepath.Dir(path), 0755)
+				err = os.WriteFile(path, data, 0644)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+
+		golden := 0
+		err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			golden++
+			name, _ := filepath.Rel(dir, strings.TrimSuffix(path, ".golden"))
+			want, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			if got, ok := files[name]; !ok {
+				t.Errorf("%s: %s not generated", dialect, name)
+			} else if !bytes.Equal(got, want) {
+				t.Errorf("%s: %s mismatch golden file, run with -update if expected:\n%s", dialect, name, got)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if golden != len(files) {
+			t.Errorf("%s: %d files generated, %d golden files", dialect, len(files), golden)
+		}
+		typeCheck(t, dialect, "example.com/app/model", files)
+	}
+}
+
+// goldenImporter importer of generated packages, stubs of testdata/stub and packages of go.mod
+type goldenImporter struct {
+	fset   *token.FileSet
+	files  map[string][]*ast.File // 生成的包
+	pkgs   map[string]*types.Package
+	errs   map[string]error // 失败的包只报告一次
+	source types.ImporterFrom
+}
+
+func (imp *goldenImporter) Import(path string) (*types.Package, error) {
+	if pkg, ok := imp.pkgs[path]; ok {
+		return pkg, nil
+	}
+	if _, ok := imp.errs[path]; ok {
+		return nil, errors.New("import of " + path + " failed")
+	}
+	files, ok := imp.files[path]
+	if !ok {
+		// go.mod中没有的第三方包使用桩代码
+		list, _ := filepath.Glob(filepath.Join("testdata", "stub", filepath.FromSlash(path), "*.go"))
+		if len(list) == 0 {
+			return imp.source.ImportFrom(path, ".", 0)
+		}
+		for _, name := range list {
+			f, err := parser.ParseFile(imp.fset, name, nil, 0)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, f)
+		}
+	}
+
+	var errs []error
+	conf := types.Config{Importer: imp, Error: func(err error) { errs = append(errs, err) }}
+	pkg, _ := conf.Check(path, imp.fset, files, nil)
+	if len(errs) > 0 {
+		imp.errs[path] = errors.Join(errs...)
+		return nil, imp.errs[path]
+	}
+	imp.pkgs[path] = pkg
+	return pkg, nil
+}
+
+// sourceImporter shared by typeCheck, packages of go.mod are type checked from source only once
+var (
+	sourceFset     = token.NewFileSet()
+	sourceImporter = importer.ForCompiler(sourceFset, "source", nil).(types.ImporterFrom)
+)
+
+// protoStub messages of proto as code generated by protoc-gen-go, only fields and getters
+func protoStub(t *testing.T, proto []byte) []byte {
+	goTypes := map[string]string{
+		"bytes":                     "[]byte",
+		"google.protobuf.Timestamp": "*timestamppb.Timestamp",
+		"google.protobuf.Value":     "*structpb.Value",
+		"google.protobuf.FieldMask": "*fieldmaskpb.FieldMask",
+	}
+	buf := new(bytes.Buffer)
+	var message string
+	var fields, getters []string
+	for _, line := range strings.Split(string(proto), "\n") {
+		line, _, _ = strings.Cut(strings.TrimSpace(line), "//")
+		words := strings.Fields(line)
+		switch {
+		case len(words) == 3 && words[0] == "message":
+			message = words[1]
+		case line == "}" && message != "":
+			fmt.Fprintf(buf, "type %s struct {\n%s}\n\n%s\n", message, strings.Join(fields, ""), strings.Join(getters, ""))
+			message, fields, getters = "", nil, nil
+		case message != "" && len(words) >= 4:
+			repeated := words[0] == "repeated"
+			if repeated {
+				words = words[1:]
+			}
+			typ, ok := goTypes[words[0]]
+			if !ok && strings.ToLower(words[0]) == words[0] {
+				typ = words[0] // 标量类型
+			} else if !ok {
+				typ = "*" + words[0]
+			}
+			if repeated {
+				typ = "[]" + typ
+			}
+			name := protoGoName(words[1])
+			fields = append(fields, fmt.Sprintf("%s %s\n", name, typ))
+			getters = append(getters, fmt.Sprintf("func (x *%s) Get%s() (v %s) {\nif x != nil {\nv = x.%s\n}\nreturn\n}\n\n", message, name, typ, name))
+		}
+	}
+	data, err := imports.Process("", append([]byte("package pb\n\n"), buf.Bytes()...), nil)
+	if err != nil {
+		t.Fatalf("%v:\n%s", err, buf)
+	}
+	return data
+}
+
+// typeCheck type check generated go files of importPath, messages of proto are from protoStub
+func typeCheck(t *testing.T, dialect Dialect, importPath string, files map[string][]byte) {
+	fset := sourceFset
+	imp := &goldenImporter{
+		fset:   fset,
+		files:  make(map[string][]*ast.File),
+		pkgs:   make(map[string]*types.Package),
+		errs:   make(map[string]error),
+		source: sourceImporter,
+	}
+	for name, data := range files {
+		dir := filepath.ToSlash(filepath.Dir(name))
+		if filepath.Ext(name) == ".proto" {
+			name, data = name+".pb.go", protoStub(t, data)
+		} else if filepath.Ext(name) != ".go" {
+			continue
+		}
+		f, err := parser.ParseFile(fset, name, data, 0)
+		if err != nil {
+			t.Errorf("%s: %v", dialect, err)
+			continue
+		}
+		path := importPath
+		if dir != "." {
+			path += "/" + dir
+		}
+		imp.files[path] = append(imp.files[path], f)
+	}
+	paths := make([]string, 0, len(imp.files))
+	for path := range imp.files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		if _, err := imp.Import(path); err != nil && imp.errs[path] == err {
+			t.Errorf("%s: %s does not compile:\n%v", dialect, path, err)
+		}
+	}
+}
+
+// methodTypes signatures without param names of methods, eg. InsertUser: (*UserObj) error
+func methodTypes(t *testing.T, src []byte, recv string) map[string]string {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	typeString := func(list *ast.FieldList) string {
+		var types []string
+		for _, f := range list.List {
+			buf := new(bytes.Buffer)
+			_ = printer.Fprint(buf, fset, f.Type)
+			for i := 0; i < len(f.Names) || i == 0; i++ {
+				types = append(types, strings.ReplaceAll(buf.String(), "internal.", ""))
+			}
+		}
+		return strings.Join(types, ", ")
+	}
+	methods := make(map[string]string)
+	add := func(name string, ft *ast.FuncType) {
+		methods[name] = "(" + typeString(ft.Params) + ")"
+		if ft.Results != nil {
+			methods[name] += " (" + typeString(ft.Results) + ")"
+		}
+	}
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncDecl:
+			if n.Recv != nil && n.Name.IsExported() {
+				if star, ok := n.Recv.List[0].Type.(*ast.StarExpr); ok && star.X.(*ast.Ident).Name == recv {
+					add(n.Name.Name, n.Type)
+				}
+			}
+		case *ast.TypeSpec:
+			if it, ok := n.Type.(*ast.InterfaceType); ok && n.Name.Name == recv {
+				for _, m := range it.Methods.List {
+					add(m.Names[0].Name, m.Type.(*ast.FuncType))
+				}
+			}
+		}
+		return true
+	})
+	return methods
+}
+
+func TestGenerateFake(t *testing.T) {
+	for _, dialect := range []Dialect{Postgres, MongoDB} {
+		files := generateTestdata(t, dialect)
+		for _, name := range []string{"user", "user_login", "fido_credential"} {
+			table := strcase.ToCamel(name)
+			repo := methodTypes(t, files[filepath.Join("internal", name+".go")], table+"Repository")
+			fake := methodTypes(t, files[filepath.Join("fake", name+".go")], table)
+			if len(repo) < 5 {
+				t.Fatalf("%s: methods of %sRepository: %v", dialect, table, repo)
+			}
+			for m, sig := range repo {
+				if fake[m] != sig {
+					t.Errorf("%s: %s of fake %s is %q, want %q", dialect, m, table, fake[m], sig)
+				}
+			}
+		}
+		if !strings.Contains(string(files["model.go"]), "	UserRepository\n") ||
+			!regexp.MustCompile(`UserRepository:\s+NewUser\(\),`).Match(files[filepath.Join("fake", "model.go")]) {
+			t.Fatalf("%s: GlobalModel of repository:\n%s", dialect, files["model.go"])
+		}
+	}
+}
+
+func TestGenerateFixture(t *testing.T) {
+	schema, err := Parse(strings.NewReader(`CREATE TABLE post (
+	    id      SERIAL      NOT NULL,
+	    user_id INTEGER     NOT NULL REFERENCES "account" (id),
+	    title   VARCHAR(16) NOT NULL,
+	    status  TEXT        NOT NULL CHECK (status IN ('draft', 'published')),
+	    slug    TEXT,
+	    body    TEXT,
+	    PRIMARY KEY (id)
+	);
+	CREATE UNIQUE INDEX idx_post_slug ON post (slug);`), Postgres)
+	if err != nil {
+		t.Fatal(err)
+	}
+	account, err := Parse(strings.NewReader(`CREATE TABLE account (
+	    id   SERIAL NOT NULL,
+	    name TEXT   NOT NULL,
+	    PRIMARY KEY (id)
+	);`), Postgres)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema.Tables = append(schema.Tables, account.Tables...)
+	for _, v := range schema.Tables {
+		v.File = v.Name
+	}
+	files, err := Generate(schema, Options{ImportPath: "example.com/app/model", Fixture: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	post := string(files[filepath.Join("fixture", "post.go")])
+	for _, v := range []string{
+		`UserId: int(n),`,
+		`Title:  fixtureString("title", n, 16),`,
+		`Status: "draft",`,
+		`Slug:   fixtureString("slug", n, 0),`,
+	} {
+		if !strings.Contains(post, v) {
+			t.Fatalf("%q not found:\n%s", v, post)
+		}
+	}
+	if strings.Contains(post, "Body:") || strings.Contains(post, "ID:") {
+		t.Fatalf("nullable or serial column filled:\n%s", post)
+	}
+	seed := string(files[filepath.Join("fixture", "seed.go")])
+	if strings.Index(seed, `{"account", `) > strings.Index(seed, `{"post", `) {
+		t.Fatalf("referenced table should be seeded first:\n%s", seed)
+	}
+}
+
+func TestGenerateTenant(t *testing.T) {
+	ddl := `CREATE TABLE note (
+	    id        SERIAL  NOT NULL,
+	    tenant_id INTEGER NOT NULL,
+	    title     TEXT    NOT NULL,
+	    PRIMARY KEY (id)
+	);
+	CREATE INDEX idx_note_title ON note (title);`
+	for _, dialect := range []Dialect{Postgres, MongoDB} {
+		schema, err := Parse(strings.NewReader(ddl), dialect)
+		if err != nil {
+			t.Fatal(err)
+		}
+		schema.Tables[0].File = "note"
+		files, err := Generate(schema, Options{ImportPath: "example.com/app/model", Handlers: true, Fake: true, Tenant: "tenant_id"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		internal := string(files[filepath.Join("internal", "note.go")])
+		for _, v := range []string{
+			`InsertNote(ctx context.Context, obj *NoteObj) error`,
+			`CountNoteByTitle(ctx context.Context, title string) (int64, error)`,
+			`return db.ErrCrossTenant`,
+		} {
+			if !strings.Contains(internal, v) {
+				t.Fatalf("%s: %q not found:\n%s", dialect, v, internal)
+			}
+		}
+		scope := `d.scope(ctx).Where("title=?", title)`
+		if dialect == MongoDB {
+			scope = `filter["tenant_id"] = tenant`
+		}
+		if !strings.Contains(internal, scope) {
+			t.Fatalf("%s: %q not found:\n%s", dialect, scope, internal)
+		}
+		handler := string(files[filepath.Join("handler", "note.go")])
+		if !strings.Contains(handler, `h.Model.SelectNote(c.Request.Context(), id)`) || strings.Contains(handler, "TenantId") {
+			t.Fatalf("%s: tenant of handler is not from context:\n%s", dialect, handler)
+		}
+		fake := string(files[filepath.Join("fake", "note.go")])
+		if !strings.Contains(fake, `v.Title == title && v.TenantId == tenant`) {
+			t.Fatalf("%s: fake is not scoped by tenant:\n%s", dialect, fake)
+		}
+	}
+
+	schema, err := Parse(strings.NewReader(strings.Replace(ddl, "tenant_id INTEGER", "tenant_id BOOLEAN", 1)), Postgres)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = Generate(schema, Options{Tenant: "tenant_id"})
+	if err == nil {
+		t.Fatal("unsupported tenant type not detected")
+	}
+}
+
+func TestGenerateCache(t *testing.T) {
+	schema, err := Parse(strings.NewReader(`CREATE TABLE account (
+	    id    SERIAL NOT NULL,
+	    email TEXT   NOT NULL,
+	    name  TEXT   NOT NULL,
+	    PRIMARY KEY (id)
+	);
+	CREATE UNIQUE INDEX idx_account_email ON account (email);
+	CREATE UNIQUE INDEX idx_account_name ON account (lower(name));`), Postgres)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema.Tables[0].File = "account"
+	files, err := Generate(schema, Options{ImportPath: "example.com/app/model", Cache: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	account := string(files[filepath.Join("cache", "account.go")])
+	for _, v := range []string{
+		`r.key(ctx, "Email", obj.Email),`,
+		`cachehelper.Fetch(context.Background(), r.cache, r.key(context.Background(), "Email", email)`,
+		`return r.AccountRepository.SelectAccountByName(name)`,
+	} {
+		if !strings.Contains(account, v) {
+			t.Fatalf("%q not found:\n%s", v, account)
+		}
+	}
+	// 表达式索引不缓存, 仅在写入时失效对象的key
+	if strings.Contains(account, "func (r *Account) SelectAccountByName") {
+		t.Fatalf("expression index is cached:\n%s", account)
+	}
+	if !strings.Contains(string(files[filepath.Join("cache", "model.go")]), "AccountRepository: NewAccount(m.AccountRepository, cache, ttl)") {
+		t.Fatal("cached GlobalModel not generated")
+	}
+}
+
+func TestGenerateAudit(t *testing.T) {
+	ddl := `CREATE TABLE note (
+	    id         SERIAL      NOT NULL,
+	    title      TEXT        NOT NULL,
+	    creator    VARCHAR(64) NOT NULL,
+	    modifier   VARCHAR(64),
+	    created_at TIMESTAMP   NOT NULL,
+	    ctime      TIMESTAMP   NOT NULL,
+	    PRIMARY KEY (id)
+	);
+	CREATE UNIQUE INDEX uk_note_title ON note (title);`
+	opts := Options{
+		ImportPath: "example.com/app/model",
+		Handlers:   true,
+		Fake:       true,
+		Audit:      AuditColumns{CreatedAt: "ctime", CreatedBy: "creator", UpdatedBy: "modifier"},
+		History:    true,
+	}
+	for _, dialect := range []Dialect{Postgres, MongoDB} {
+		schema, err := Parse(strings.NewReader(ddl), dialect)
+		if err != nil {
+			t.Fatal(err)
+		}
+		schema.Tables[0].File = "note"
+		files, err := Generate(schema, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		internal := string(files[filepath.Join("internal", "note.go")])
+		for _, v := range []string{
+			`obj.Creator = actor`,
+			`update = d.audit(ctx, update)`,
+			`ListNoteHistory(ctx context.Context, id `,
+			`return d.record(ctx, db.HistoryDelete, `,
+		} {
+			if dialect == Postgres && v == `update = d.audit(ctx, update)` {
+				v = `fields = d.audit(ctx, fields)`
+			}
+			if !strings.Contains(internal, v) {
+				t.Fatalf("%s: %q not found:\n%s", dialect, v, internal)
+			}
+		}
+		if dialect == Postgres {
+			if !strings.Contains(internal, `gorm:"column:ctime;not null;autoCreateTime"`) ||
+				strings.Contains(internal, `column:created_at;not null;autoCreateTime`) {
+				t.Fatalf("configured created_at column is not auto time:\n%s", internal)
+			}
+			if !strings.Contains(internal, `return tx.Where("title=?", title)`) {
+				t.Fatalf("update by index is not recorded:\n%s", internal)
+			}
+		}
+		handler := string(files[filepath.Join("handler", "note.go")])
+		if strings.Contains(handler, "Creator") || !strings.Contains(handler, `h.Model.SelectNote(c.Request.Context(), id)`) {
+			t.Fatalf("%s: actor of handler is not from context:\n%s", dialect, handler)
+		}
+		fake := string(files[filepath.Join("fake", "note.go")])
+		if !strings.Contains(fake, `func (r *Note) ListNoteHistory(`) {
+			t.Fatalf("%s: fake has no history:\n%s", dialect, fake)
+		}
+	}
+
+	schema, err := Parse(strings.NewReader(ddl), Postgres)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = Generate(schema, Options{Audit: AuditColumns{CreatedBy: "created_at"}})
+	if err == nil {
+		t.Fatal("unsupported audit column type not detected")
+	}
+}
+
+func TestGenerateQuery(t *testing.T) {
+	for name, want := range map[string]string{"User": "Users", "Category": "Categories", "Address": "Addresses", "Day": "Days"} {
+		if got := plural(name); got != want {
+			t.Fatalf("plural of %s: %s", name, got)
+		}
+	}
+	ddl := `CREATE TABLE category (
+	    id    SERIAL NOT NULL,
+	    query TEXT   NOT NULL,
+	    PRIMARY KEY (id)
+	);`
+	for _, dialect := range []Dialect{Postgres, MongoDB} {
+		schema, err := Parse(strings.NewReader(ddl), dialect)
+		if err != nil {
+			t.Fatal(err)
+		}
+		schema.Tables[0].File = "category"
+		files, err := Generate(schema, Options{ImportPath: "example.com/app/model", Fake: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		internal := string(files[filepath.Join("internal", "category.go")])
+		column := `QueryColumn: db.Column[string]{Name: "query"},`
+		for _, v := range []string{
+			`var Categories = CategoryColumns{`,
+			column,
+			`FindCategory(q *db.Query[CategoryObj]) ([]*CategoryObj, error)`,
+			`CountCategory(q *db.Query[CategoryObj]) (int64, error)`,
+		} {
+			if !strings.Contains(internal, v) {
+				t.Fatalf("%s: %q not found:\n%s", dialect, v, internal)
+			}
+		}
+		if custom := string(files["category.go"]); !strings.Contains(custom, "var Categories = internal.Categories") {
+			t.Fatalf("%s: typed columns are not exported:\n%s", dialect, custom)
+		}
+		if fake := string(files[filepath.Join("fake", "category.go")]); !strings.Contains(fake, `case "query":`) {
+			t.Fatalf("%s: fake has no column value:\n%s", dialect, fake)
+		}
+	}
+}
+
+func TestLint(t *testing.T) {
+	files := map[string][]byte{
+		"a.sql": []byte(`CREATE TABLE account (
+	    id    SERIAL  NOT NULL,
+	    email TEXT,
+	    type  TEXT    NOT NULL,
+	    PRIMARY KEY (id)
+	);
+	COMMENT ON TABLE account IS 'account';
+	COMMENT ON COLUMN account.id IS 'id';
+	CREATE UNIQUE INDEX idx_email ON account (email);
+	CREATE INDEX idx_missing ON account (missing);
+	CREATE INDEX idx_log_at ON log (at);`),
+		"b.sql": []byte(`CREATE TABLE log (
+	    at TIMESTAMP NOT NULL,
+	    ip INET
+	);`),
+		"c.sql": []byte(`CREATE TABLE event (
+	    id SERIAL NOT NULL,
+	    PRIMARY KEY (id)
+	);
+	COMMENT ON TABLE event IS 'event';
+	COMMENT ON COLUMN event.id IS 'id';
+	CREATE INDEX idx_email ON event (id);`),
+	}
+	issues, err := Lint(files, Postgres)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]*Issue)
+	for _, v := range issues {
+		got[v.File+" "+v.Rule+" "+v.Column+v.Index] = v
+	}
+	for key, severity := range map[string]string{
+		"a.sql missing-comment email":            SeverityWarning,
+		"a.sql reserved-name type":               SeverityError,
+		"a.sql nullable-unique emailidx_email":   SeverityWarning,
+		"a.sql unknown-index-column idx_missing": SeverityError,
+		"a.sql foreign-index idx_log_at":         SeverityWarning,
+		"b.sql unsupported-type ip":              SeverityError,
+		"c.sql duplicate-index idx_email":        SeverityError,
+	} {
+		v, ok := got[key]
+		if !ok {
+			t.Fatalf("%s not found: %v", key, issues)
+		}
+		if v.Severity != severity {
+			t.Fatalf("%s: %s", key, v.Severity)
+		}
+	}
+	if v := got["a.sql foreign-index idx_log_at"]; !strings.Contains(v.Message, "defined in b.sql") {
+		t.Fatal(v.Message)
+	}
+	if _, ok := got["a.sql missing-comment id"]; ok {
+		t.Fatal("commented column reported")
+	}
+
+	// 没有主键, json输出
+	files = map[string][]byte{"b.sql": []byte(`CREATE TABLE log (
+	    at TIMESTAMP NOT NULL
+	);
+	CREATE INDEX idx_log_at ON log (at);
+	CREATE INDEX idx_nope_at ON nope (at);`)}
+	issues, err = Lint(files, Postgres)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(issues)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []string{`"rule":"no-primary-key"`, `"file":"b.sql"`, `"rule":"foreign-index","severity":"error"`} {
+		if !strings.Contains(string(data), v) {
+			t.Fatalf("%s not found: %s", v, data)
+		}
+	}
+}
+
+func TestWriteGenerated(t *testing.T) {
+	dst := t.TempDir()
+	generate := func(ddls ...string) map[string][]byte {
+		schema := &Schema{Dialect: Postgres}
+		for _, ddl := range ddls {
+			sub, err := Parse(strings.NewReader(ddl), Postgres)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sub.Tables[0].File = sub.Tables[0].Name
+			schema.Tables = append(schema.Tables, sub.Tables...)
+		}
+		files, err := Generate(schema, Options{ImportPath: "example.com/app/model"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return files
+	}
+	write := func(files map[string][]byte, want string) {
+		stats, err := writeGenerated(dst, files, map[string]bool{"account.go": true, "event.go": true}, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stats.String() != want {
+			t.Fatalf("want %s, got %s", want, stats)
+		}
+	}
+	account := `CREATE TABLE account (
+	    id   SERIAL NOT NULL,
+	    name TEXT   NOT NULL,
+	    PRIMARY KEY (id)
+	);`
+	event := `CREATE TABLE event (
+	    id SERIAL NOT NULL,
+	    PRIMARY KEY (id)
+	);`
+	files := generate(account, event)
+	if hash := headerHash(files[filepath.Join("internal", "account.go")]); len(hash) != 64 {
+		t.Fatalf("source hash not found: %q", hash)
+	}
+	write(files, "created: 5, updated: 0, removed: 0, unchanged: 0")
+	write(generate(account, event), "created: 0, updated: 0, removed: 0, unchanged: 3")
+
+	// 修改的表及model.go
+	account = strings.Replace(account, "name TEXT  ", "name VARCHAR", 1)
+	write(generate(account, event), "created: 0, updated: 2, removed: 0, unchanged: 1")
+
+	// 删除event.sql
+	write(generate(account), "created: 0, updated: 1, removed: 1, unchanged: 1")
+	if _, err := os.Stat(filepath.Join(dst, "internal", "event.go")); !os.IsNotExist(err) {
+		t.Fatal("orphaned internal file not removed")
+	}
+	if _, err := os.Stat(filepath.Join(dst, "event.go")); err != nil {
+		t.Fatal("custom file removed")
+	}
+}
+
+func TestGenerateJobs(t *testing.T) {
+	schema := &Schema{Dialect: Postgres}
+	for _, name := range []string{"account", "event", "log"} {
+		sub, err := Parse(strings.NewReader(`CREATE TABLE `+name+` (
+		    tenant_id SERIAL NOT NULL,
+		    PRIMARY KEY (tenant_id)
+		);`), Postgres)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sub.Tables[0].File = name
+		schema.Tables = append(schema.Tables, sub.Tables...)
+	}
+	// 报告所有表的错误
+	_, err := Generate(schema, Options{Tenant: "tenant_id", Jobs: 2})
+	if err == nil {
+		t.Fatal("tenant primary key accepted")
+	}
+	for _, name := range []string{"Account", "Event", "Log"} {
+		if !strings.Contains(err.Error(), "primary key of "+name) {
+			t.Fatalf("error of %s not found: %s", name, err)
+		}
+	}
+	if _, err = Generate(schema, Options{Jobs: 8}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGenerateView(t *testing.T) {
+	ddl := `CREATE MATERIALIZED VIEW login_stats (
+	    user_id,     -- VARCHAR(64) NOT NULL
+	    login_count  -- BIGINT
+	) AS
+	SELECT user_id, count(*) FROM user_login GROUP BY user_id
+	WITH DATA;
+	COMMENT ON MATERIALIZED VIEW login_stats IS '登录统计';
+	CREATE UNIQUE INDEX idx_login_stats_user_id ON login_stats (user_id);`
+	schema, err := Parse(strings.NewReader(ddl), Postgres)
+	if err != nil {
+		t.Fatal(err)
+	}
+	view := schema.Tables[0]
+	if !view.View || !view.Materialized || view.Comment != "登录统计" || view.PrimaryKey != nil {
+		t.Fatalf("invalid view: %+v", view)
+	}
+	if c := view.Columns[0]; c.GoType != "string" || !c.NotNull || view.Columns[1].GoType != "int64" {
+		t.Fatalf("invalid column: %+v", c)
+	}
+	view.File = "login_stats"
+	files, err := Generate(schema, Options{ImportPath: "example.com/app/model", Handlers: true, Fake: true, History: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	internal := string(files[filepath.Join("internal", "login_stats.go")])
+	for _, v := range []string{
+		`RefreshLoginStats() error`,
+		"REFRESH MATERIALIZED VIEW CONCURRENTLY \"login_stats\"",
+		`SelectLoginStatsByUserId(userId string) (*LoginStatsObj, error)`,
+		`FindLoginStats(q *db.Query[LoginStatsObj])`,
+	} {
+		if !strings.Contains(internal, v) {
+			t.Fatalf("%q not found:\n%s", v, internal)
+		}
+	}
+	for _, v := range []string{"AutoMigrate(", "InsertLoginStats", "UpdateLoginStats", "DeleteLoginStats", "History"} {
+		if strings.Contains(internal, v) {
+			t.Fatalf("%q found in view:\n%s", v, internal)
+		}
+	}
+	if _, ok := files[filepath.Join("handler", "login_stats.go")]; ok {
+		t.Fatal("handler of view generated")
+	}
+	if fake := string(files[filepath.Join("fake", "login_stats.go")]); !strings.Contains(fake, "PutLoginStats(list ...*internal.LoginStatsObj)") {
+		t.Fatalf("fake of view:\n%s", fake)
+	}
+
+	// 普通视图的列类型在列名后
+	schema, err = Parse(strings.NewReader(`CREATE OR REPLACE VIEW active_user (
+	    id   INTEGER NOT NULL,
+	    name TEXT
+	) AS SELECT id, name FROM "user";`), Postgres)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if view = schema.Tables[0]; view.Materialized || len(view.Columns) != 2 || view.Columns[1].GoType != "string" {
+		t.Fatalf("invalid view: %+v", view)
+	}
+	for _, ddl := range []string{
+		`CREATE VIEW active_user AS SELECT id FROM "user";`,
+		`CREATE VIEW active_user (
+		    id,
+		    name -- TEXT
+		) AS SELECT id, name FROM "user";`,
+	} {
+		if _, err = Parse(strings.NewReader(ddl), Postgres); err == nil {
+			t.Fatalf("invalid view accepted: %s", ddl)
+		}
+	}
+	if _, err = Parse(strings.NewReader(`CREATE VIEW active_user (id INTEGER) AS SELECT id FROM "user";`), MongoDB); err == nil {
+		t.Fatal("view of mongodb accepted")
+	}
+}
+
+func TestInlineIndex(t *testing.T) {
+	// 表内的UNIQUE被忽略, 只有CREATE INDEX生成索引
+	schema, err := Parse(strings.NewReader(ddlSQLs[0]), Postgres)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user := schema.Tables[0]; len(user.Indexes) != 0 || len(user.Columns) != 5 {
+		t.Fatalf("in-table UNIQUE parsed: %+v %+v", user.Indexes, user.Columns)
+	}
+	issues, err := Lint(map[string][]byte{"user.sql": []byte(ddlSQLs[0])}, Postgres)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, v := range issues {
+		found = found || v.Rule == LintInlineIndex && v.Severity == SeverityWarning
+	}
+	if !found {
+		t.Fatalf("in-table UNIQUE not reported: %v", issues)
+	}
+
+	schema, err = Parse(strings.NewReader(userDDL), Postgres)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if idx := schema.Tables[0].Indexes; len(idx) != 1 || !reflect.DeepEqual(idx[0].Columns, []string{"email", "age"}) {
+		t.Fatalf("indexes: %+v", idx)
+	}
+	files, err := Generate(schema, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	internal := string(files[filepath.Join("internal", "user.go")])
+	for _, v := range []string{"column:email;not null;uniqueIndex:idx_user_email_age,priority:1", "column:age;not null;uniqueIndex:idx_user_email_age,priority:2"} {
+		if !strings.Contains(internal, v) {
+			t.Fatalf("%q not found:\n%s", v, internal)
+		}
+	}
+}
+
+// reverseDDL generate internal files of ddl, then reverse them to DDL by file name
+func reverseDDL(t *testing.T, ddl string, extra map[string]string) (*Schema, map[string][]byte) {
+	t.Helper()
+	schema, err := Parse(strings.NewReader(ddl), Postgres)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := Generate(schema, Options{ImportPath: "example.com/app/model"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	sources := map[string]string{"go.mod": "module example.com/app\n"}
+	for name, data := range files {
+		if filepath.Dir(name) == "internal" {
+			sources[filepath.Base(name)] = string(data)
+		}
+	}
+	for name, data := range extra {
+		sources[name] = data
+	}
+	for name, data := range sources {
+		err = os.WriteFile(filepath.Join(dir, name), []byte(data), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	reversed, err := Reverse(dir, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return schema, reversed
+}
+
+func TestReverseComment(t *testing.T) {
+	schema, ddl := reverseDDL(t, `CREATE TABLE doc (
+	    id    SERIAL NOT NULL,
+	    title TEXT   NOT NULL,
+	    PRIMARY KEY (id)
+	);
+	COMMENT ON TABLE doc IS 'it''s a doc; -- v2';
+	COMMENT ON COLUMN doc.title IS 'a "c" d\e; it''s';`, map[string]string{
+		"note.go": "package internal\n\n" +
+			"// NoteObj note of user\ntype NoteObj struct {\n" +
+			"\tID   int    `gorm:\"column:id;primaryKey;autoIncrement\"`\n" +
+			"\tText string `gorm:\"column:text;comment:it's\"`\n" +
+			"\tMemo string `gorm:\"column:memo;comment:a\\\\;b\"`\n}\n",
+	})
+	if !strings.Contains(string(ddl["doc.sql"]), `COMMENT ON TABLE "doc" IS 'it''s a doc; -- v2';`) {
+		t.Fatalf("table comment:\n%s", ddl["doc.sql"])
+	}
+	reversed, err := Parse(bytes.NewReader(ddl["doc.sql"]), Postgres)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, got := schema.Tables[0], reversed.Tables[0]
+	if got.Comment != "it's a doc; -- v2" || got.Columns[1].Comment != `a "c" d\e; it's` || !reflect.DeepEqual(want.Columns, got.Columns) {
+		t.Fatalf("comment mismatch:\n%s\n%+v", ddl["doc.sql"], got.Columns[1])
+	}
+
+	// 含;的注释可以重新解析
+	reversed, err = Parse(bytes.NewReader(ddl["note.sql"]), Postgres)
+	if err != nil {
+		t.Fatalf("%v:\n%s", err, ddl["note.sql"])
+	}
+	comments := map[string]string{"": reversed.Tables[0].Comment}
+	for _, c := range reversed.Tables[0].Columns {
+		comments[c.Name] = c.Comment
+	}
+	if want := map[string]string{"": "note of user", "id": "", "text": "it's", "memo": "a;b"}; !reflect.DeepEqual(comments, want) {
+		t.Fatalf("comments %v, want %v:\n%s", comments, want, ddl["note.sql"])
+	}
+
+	// 兼容双引号的注释
+	quoted, err := Parse(strings.NewReader(ddlSQLs[1]), Postgres)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := quoted.Tables[0]; c.Comment != "凭证表" || c.Columns[0].Comment != "自增ID" {
+		t.Fatalf("double quoted comment: %q %q", c.Comment, c.Columns[0].Comment)
+	}
+}
+
+func TestReverseIndexOptions(t *testing.T) {
+	schema, ddl := reverseDDL(t, `CREATE TABLE doc (
+	    id         SERIAL    NOT NULL,
+	    title      TEXT      NOT NULL,
+	    tags       TEXT[],
+	    score      INTEGER   NOT NULL,
+	    deleted_at TIMESTAMP,
+	    PRIMARY KEY (id)
+	);
+	CREATE UNIQUE INDEX idx_doc_title ON doc (lower(title)) WHERE deleted_at IS NULL;
+	CREATE INDEX idx_doc_tags ON doc USING gin (tags);
+	CREATE INDEX CONCURRENTLY idx_doc_score_title ON doc (score DESC, title);
+	CREATE INDEX idx_doc_score ON doc (score) WHERE coalesce(score, 0) > 0;`, nil)
+	raw := string(ddl["doc.sql"])
+	for _, v := range []string{
+		`CREATE UNIQUE INDEX idx_doc_title ON "doc" ((lower(title))) WHERE deleted_at IS NULL;`,
+		`CREATE INDEX idx_doc_tags ON "doc" USING gin (tags);`,
+		`CREATE INDEX CONCURRENTLY idx_doc_score_title ON "doc" (score DESC, title);`,
+		`CREATE INDEX idx_doc_score ON "doc" (score) WHERE coalesce(score, 0) > 0;`,
+	} {
+		if !strings.Contains(raw, v) {
+			t.Fatalf("%q not found:\n%s", v, raw)
+		}
+	}
+	reversed, err := Parse(strings.NewReader(raw), Postgres)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, got := schema.Tables[0], reversed.Tables[0]
+	if !reflect.DeepEqual(want.Columns, got.Columns) {
+		t.Fatalf("columns mismatch:\n%+v\n%+v", want.Columns, got.Columns)
+	}
+	sortIndexes := func(list []*Index) {
+		sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	}
+	sortIndexes(want.Indexes)
+	sortIndexes(got.Indexes)
+	if !reflect.DeepEqual(want.Indexes, got.Indexes) {
+		t.Fatalf("indexes mismatch:\n%s", raw)
+	}
+}
+
+func TestParseCreateModifiers(t *testing.T) {
+	schema, err := Parse(strings.NewReader(`CREATE TEMP TABLE session (
+	    id   SERIAL NOT NULL,
+	    data TEXT,
+	    PRIMARY KEY (id)
+	);`), Postgres)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if table := schema.Tables[0]; table.Name != "session" || table.View || len(table.Columns) != 2 {
+		t.Fatalf("temp table: %+v", table)
+	}
+	schema, err = Parse(strings.NewReader(`CREATE OR REPLACE TEMP VIEW recent_session (
+	    id INTEGER NOT NULL
+	) AS SELECT id FROM session;`), Postgres)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if view := schema.Tables[0]; view.Name != "recent_session" || !view.View {
+		t.Fatalf("view: %+v", view)
+	}
+	_, err = Parse(strings.NewReader(`CREATE OR REPLACE FUNCTION now_utc() RETURNS TIMESTAMP AS $$ SELECT now() $$ LANGUAGE SQL;`), Postgres)
+	if err == nil || !strings.Contains(err.Error(), "unsupported operation: CREATE OR REPLACE FUNCTION") {
+		t.Fatal(err)
+	}
 }
@@ -4,8 +4,11 @@ package model
 import (
 	"bytes"
 	_ "embed" // embed
+	"errors"
 	"fmt"
-	"os"
+	"regexp"
+	"strconv"
+	"strings"
 
 	"github.com/iancoleman/strcase"
 	"golang.org/x/tools/imports"
@@ -20,9 +23,53 @@ var customMgoTmpl string
 //go:embed template/model_mgo.tmpl
 var modelMgoTmpl string
 
-type mongodbGenerator struct{}
+type mongodbGenerator struct {
+	validator string
+}
+
+type mongodbParams struct {
+	*commandParams
+
+	ObjectID bool                  // 主键为primitive.ObjectID, 由driver生成
+	Update   []*mongodbUpdateField // 可更新字段, 不含主键
+
+	Validator        string // $jsonSchema validator, 为空不校验
+	ValidationAction string // error/warn
+}
 
-func newMongoDBGenerator() (*mongodbGenerator, error) {
+type mongodbUpdateField struct {
+	Name string
+	Type string
+	Bson string // nil不更新, eg. name,omitempty
+}
+
+// mongoFields bson tag of fields, the primary key is mapped to _id,
+// SERIAL or missing primary key is primitive.ObjectID
+func mongoFields(params *commandParams) {
+	if params.Primary == nil {
+		f := &field{column: "_id", sqlType: "OBJECTID", notNull: true, Name: "ID"}
+		params.Fields = append([]*field{f}, params.Fields...)
+		params.Primary = &primaryKey{field: f, Autoincrement: true}
+	}
+	if params.Primary.Autoincrement {
+		params.Primary.Type = "primitive.ObjectID"
+	}
+	for _, f := range params.Fields {
+		f.Bson = f.column
+		if f == params.Primary.field {
+			f.Bson = "_id"
+		}
+		// 可空字段及driver生成的_id
+		if !f.notNull || (f == params.Primary.field && params.Primary.Autoincrement) {
+			f.Bson += ",omitempty"
+		}
+	}
+}
+
+func newMongoDBGenerator(validator string) (*mongodbGenerator, error) {
+	if validator != "" && validator != ValidatorStrict && validator != ValidatorWarn {
+		return nil, errors.New("unsupported mongodb validator: " + validator)
+	}
 	// template
 	err := ParseTemplate("internalMgoTmpl", internalMgoTmpl)
 	if err != nil {
@@ -36,81 +83,265 @@ func newMongoDBGenerator() (*mongodbGenerator, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &mongodbGenerator{}, nil
+	return &mongodbGenerator{validator: validator}, nil
 }
 
-func (mgo *mongodbGenerator) generateInternalFile(path string, params *commandParams) error {
+func (mgo *mongodbGenerator) generateInternalFile(params *commandParams) ([]byte, error) {
 	added := make(map[string]bool)
 	buf := new(bytes.Buffer)
 	buf.WriteString("	var idxs []mongo.IndexModel\n")
 	for _, v := range params.Fields {
 		for _, v := range v.indexs {
+			if !v.uniqueIndex && !v.normalIndex {
+				continue
+			}
 			var keys string
 			for i, vv := range v.indexFields {
+				if v.exprs != nil && v.exprs[i] != "" {
+					return nil, errors.New("expression index is not supported by mongodb: " + v.indexName)
+				}
 				if i != 0 {
 					keys += ","
 				}
-				name := strcase.ToSnakeWithIgnore(vv.Name, "sha1")
-				keys += fmt.Sprintf("{Key: \"%s\", Value: 1}", name)
+				value := "1"
+				if v.text {
+					value = `"text"`
+				} else if v.sorts != nil && strings.HasPrefix(v.sorts[i], "DESC") {
+					value = "-1"
+				}
+				keys += fmt.Sprintf("{Key: \"%s\", Value: %s}", vv.column, value)
 			}
 			if added[keys] {
 				continue
 			}
-			switch {
-			case v.uniqueIndex:
-				buf.WriteString(fmt.Sprintf(`
-	idxs = append(idxs, mongo.IndexModel{
-		Keys: bson.D{%s},
-		Options: options.Index().SetUnique(true),
-	})
-`, keys))
+			added[keys] = true
 
-			case v.normalIndex:
-				buf.WriteString(fmt.Sprintf(`
+			opts := fmt.Sprintf("options.Index().SetName(%q)", v.indexName)
+			if v.uniqueIndex {
+				opts += ".SetUnique(true)"
+			}
+			if v.sparse {
+				opts += ".SetSparse(true)"
+			}
+			if v.ttl {
+				opts += fmt.Sprintf(".SetExpireAfterSeconds(%d)", v.expireAfterSeconds)
+			}
+			if v.where != "" {
+				filter, err := mongoPartialFilter(v.where)
+				if err != nil {
+					return nil, fmt.Errorf("index %s: %w", v.indexName, err)
+				}
+				opts += ".SetPartialFilterExpression(" + filter + ")"
+			}
+			buf.WriteString(fmt.Sprintf(`
 	idxs = append(idxs, mongo.IndexModel{
 		Keys: bson.D{%s},
+		Options: %s,
 	})
-`, keys))
-
-			default:
-				continue
-			}
-			added[keys] = true
+`, keys, opts))
 		}
 	}
-	buf.WriteString("	mgo.Collection().Indexes().CreateMany(context.Background(), idxs)\n")
+	if params.History {
+		buf.WriteString(fmt.Sprintf(`	history := mongo.IndexModel{
+		Keys:    bson.D{{Key: "object_id", Value: 1}},
+		Options: options.Index().SetName("idx_object_id"),
+	}
+	if _, err := d.DB.Collection("%s_history").Indexes().CreateOne(ctx, history); err != nil {
+		return fmt.Errorf("create indexes of %s_history: %%w", err)
+	}
+`, strcase.ToSnake(params.TableName), strcase.ToSnake(params.TableName)))
+	}
+	buf.WriteString(fmt.Sprintf(`	if len(idxs) == 0 {
+		return nil
+	}
+	_, err := d.Collection().Indexes().CreateMany(ctx, idxs)
+	if err != nil {
+		return fmt.Errorf("create indexes of %s: %%w", err)
+	}
+	return nil
+`, strcase.ToSnake(params.TableName)))
 	params.MgoIndex = buf.String()
 
 	buf.Reset()
 	mgo.generateDeleteIndexDao(params, buf)
 	mgo.generateUpdateIndexDao(params, buf)
 	mgo.generateSelectIndexDao(params, buf)
+	mgo.generateListIndexDao(params, buf)
 	params.IndexGo = buf.String()
 
+	mp := &mongodbParams{
+		commandParams: params,
+		ObjectID:      params.Primary.Type == "primitive.ObjectID",
+	}
+	for _, f := range params.Fields {
+		if f != params.Primary.field {
+			mp.Update = append(mp.Update, &mongodbUpdateField{
+				Name: f.Name,
+				Type: f.Type,
+				Bson: f.column + ",omitempty",
+			})
+		}
+	}
+	if mgo.validator != "" {
+		mp.Validator = mongoValidator(params)
+		mp.ValidationAction = "error"
+		if mgo.validator == ValidatorWarn {
+			mp.ValidationAction = "warn"
+		}
+	}
 	buf.Reset()
-	err := ExecuteTemplate(buf, "internalMgoTmpl", params)
+	err := ExecuteTemplate(buf, "internalMgoTmpl", mp)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	// imports
 	data, err := imports.Process("", buf.Bytes(), nil)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	return appendRepository(data, params.TableName)
+}
+
+// goTypeToBson go type of sqlTypeToGo to $jsonSchema bsonType, eg. "string" or bson.A{"int", "long"}
+var goTypeToBson = map[string]string{
+	"int":                `bson.A{"int", "long"}`,
+	"int32":              `"int"`,
+	"int64":              `"long"`,
+	"bool":               `"bool"`,
+	"string":             `"string"`,
+	"[]byte":             `"binData"`,
+	"time.Time":          `"date"`,
+	"json.RawMessage":    `"binData"`,
+	"db.StringArray":     `"array"`,
+	"db.Int64Array":      `"array"`,
+	"primitive.ObjectID": `"objectId"`,
+}
+
+// mongoValidator $jsonSchema validator of fields: bsonType, required of NOT NULL and enum
+func mongoValidator(params *commandParams) string {
+	var required, properties []string
+	for _, f := range params.Fields {
+		name := strings.TrimSuffix(f.Bson, ",omitempty")
+		var props []string
+		if bsonType, ok := goTypeToBson[f.Type]; ok {
+			if !f.notNull {
+				// 可空字段允许null
+				bsonType = "bson.A{" + strings.TrimSuffix(strings.TrimPrefix(bsonType, "bson.A{"), "}") + `, "null"}`
+			}
+			props = append(props, `"bsonType": `+bsonType)
+		}
+		switch f.Type {
+		case "db.StringArray":
+			props = append(props, `"items": bson.M{"bsonType": "string"}`)
+		case "db.Int64Array":
+			props = append(props, `"items": bson.M{"bsonType": bson.A{"int", "long"}}`)
+		}
+		if len(f.enum) > 0 {
+			values := make([]string, len(f.enum))
+			for i, v := range f.enum {
+				values[i] = strconv.Quote(v)
+			}
+			if !f.notNull {
+				values = append(values, "nil")
+			}
+			props = append(props, `"enum": bson.A{`+strings.Join(values, ", ")+"}")
+		}
+		if f.Comment != "" {
+			props = append(props, `"description": `+strconv.Quote(f.Comment))
+		}
+		properties = append(properties, fmt.Sprintf("%q: bson.M{%s},", name, strings.Join(props, ", ")))
+		if f.notNull {
+			required = append(required, strconv.Quote(name))
+		}
+	}
+	return fmt.Sprintf(`bson.M{"$jsonSchema": bson.M{
+		"bsonType": "object",
+		"required": bson.A{%s},
+		"properties": bson.M{
+			%s
+		},
+	}}`, strings.Join(required, ", "), strings.Join(properties, "\n"))
+}
+
+var (
+	regexpWhereAnd     = regexp.MustCompile(`(?i)\s+AND\s+`)
+	regexpWhereNotNull = regexp.MustCompile(`(?i)^"?(\w+)"?\s+IS\s+NOT\s+NULL$`)
+	regexpWhereCompare = regexp.MustCompile(`^"?(\w+)"?\s*(=|>=|<=|>|<)\s*(.+)$`)
+)
+
+// mongoPartialFilter partialFilterExpression of WHERE predicate, only supports
+// conditions joined by AND: col IS NOT NULL, col = value, col > value, etc.
+func mongoPartialFilter(where string) (string, error) {
+	operators := map[string]string{">": "$gt", ">=": "$gte", "<": "$lt", "<=": "$lte"}
+	var conds []string
+	for _, cond := range regexpWhereAnd.Split(strings.Trim(where, "() "), -1) {
+		cond = strings.TrimSpace(cond)
+		if sub := regexpWhereNotNull.FindStringSubmatch(cond); len(sub) == 2 {
+			conds = append(conds, fmt.Sprintf(`{Key: %q, Value: bson.M{"$exists": true}}`, sub[1]))
+			continue
+		}
+		sub := regexpWhereCompare.FindStringSubmatch(cond)
+		if len(sub) != 4 {
+			return "", errors.New("unsupported partial index predicate for mongodb: " + cond)
+		}
+		value, err := mongoLiteral(strings.TrimSpace(sub[3]))
+		if err != nil {
+			return "", err
+		}
+		if op, ok := operators[sub[2]]; ok {
+			value = fmt.Sprintf("bson.M{%q: %s}", op, value)
+		}
+		conds = append(conds, fmt.Sprintf("{Key: %q, Value: %s}", sub[1], value))
 	}
-	return os.WriteFile(path, data, 0644)
+	return "bson.D{" + strings.Join(conds, ", ") + "}", nil
 }
 
-func (mgo *mongodbGenerator) generateCustomFile(path string, params *commandParams) error {
+// mongoLiteral go literal of sql literal, eg. 'a' -> "a"
+func mongoLiteral(v string) (string, error) {
+	switch {
+	case strings.EqualFold(v, "TRUE"), strings.EqualFold(v, "FALSE"):
+		return strings.ToLower(v), nil
+	case len(v) >= 2 && v[0] == '\'' && v[len(v)-1] == '\'':
+		return strconv.Quote(strings.ReplaceAll(v[1:len(v)-1], "''", "'")), nil
+	}
+	if _, err := strconv.ParseFloat(v, 64); err == nil {
+		return v, nil
+	}
+	return "", errors.New("unsupported literal in partial index predicate: " + v)
+}
+
+func (mgo *mongodbGenerator) generateCustomFile(params *commandParams) ([]byte, error) {
 	buf := &bytes.Buffer{}
 	err := ExecuteTemplate(buf, "customMgoTmpl", params)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	data, err := imports.Process("", buf.Bytes(), nil)
-	if err != nil {
-		return err
+	return imports.Process("", buf.Bytes(), nil)
+}
+
+// mongoTenant ctx param, ctx and statement adding tenant condition to filter of dao method,
+// ret is the results returned before err, eg. "nil, "
+func mongoTenant(params *commandParams, ret string) (input, ctx, scope string) {
+	if params.Tenant == nil {
+		if params.Context {
+			return "ctx context.Context, ", "ctx", ""
+		}
+		return "", "context.Background()", ""
 	}
-	return os.WriteFile(path, data, 0644)
+	scope = fmt.Sprintf("	if err := d.scope(ctx, filter); err != nil {\n		return %serr\n	}\n", ret)
+	return "ctx context.Context, ", "ctx", scope
+}
+
+// mongoRecord statement of write by filter, changes are recorded to history if enabled
+func mongoRecord(params *commandParams, action, write string) string {
+	if !params.History {
+		return fmt.Sprintf("	_, err := %s\n	return err\n", write)
+	}
+	return fmt.Sprintf(`	return d.record(ctx, db.%s, filter, func(filter bson.M) error {
+		_, err := %s
+		return err
+	})
+`, action, write)
 }
 
 func (mgo *mongodbGenerator) generateDeleteIndexDao(params *commandParams, buf *bytes.Buffer) {
@@ -130,27 +361,27 @@ func (mgo *mongodbGenerator) generateDeleteIndexDao(params *commandParams, buf *
 				} else {
 					input += ", " + n + " " + vv.Type
 				}
-				name := strcase.ToSnakeWithIgnore(vv.Name, "sha1")
-				filter += fmt.Sprintf("		\"%s\": %s,\n", name, n)
+				filter += fmt.Sprintf("		\"%s\": %s,\n", vv.column, n)
 			}
 			filter += "	}\n"
-			if (!v.uniqueIndex && !v.normalIndex) || added[key] {
+			if (!v.uniqueIndex && !v.normalIndex) || v.text || added[key] {
 				continue
 			}
 			added[key] = true
 
+			ctxInput, ctx, scope := mongoTenant(params, "")
 			funcName := fmt.Sprintf("Delete%sBy%s", params.TableName, key)
 			// comments
 			buf.WriteString(fmt.Sprintf("// %s delete object\n", funcName))
 			// func
 			buf.WriteString(fmt.Sprintf("func (d %sDao) %s", params.TableName, funcName))
-			buf.WriteString(fmt.Sprintf("(%s)", input))
+			buf.WriteString(fmt.Sprintf("(%s%s)", ctxInput, input))
 			buf.WriteString(" error {\n")
 			// filter
 			buf.WriteString(filter)
+			buf.WriteString(scope)
 			// exp
-			buf.WriteString("	_, err := d.Collection().DeleteOne(context.Background(), filter)\n")
-			buf.WriteString("	return err\n")
+			buf.WriteString(mongoRecord(params, "HistoryDelete", fmt.Sprintf("d.Collection().DeleteOne(%s, filter)", ctx)))
 			// quote
 			buf.WriteString("}\n\n")
 		}
@@ -173,34 +404,35 @@ func (mgo *mongodbGenerator) generateUpdateIndexDao(params *commandParams, buf *
 				} else {
 					input += ", " + n + " " + vv.Type
 				}
-				name := strcase.ToSnakeWithIgnore(vv.Name, "sha1")
-				filter += fmt.Sprintf("	\"%s\": %s,\n", name, n)
+				filter += fmt.Sprintf("	\"%s\": %s,\n", vv.column, n)
 			}
 			filter += "	}\n"
-			if (!v.uniqueIndex && !v.normalIndex) || added[key] {
+			if (!v.uniqueIndex && !v.normalIndex) || v.text || added[key] {
 				continue
 			}
 			added[key] = true
 
-			input += ", fields map[string]interface{}"
+			input += fmt.Sprintf(", update *%sUpdate", params.TableName)
 
+			ctxInput, ctx, scope := mongoTenant(params, "")
 			funcName := fmt.Sprintf("Update%sBy%s", params.TableName, key)
 			// comments
 			buf.WriteString(fmt.Sprintf("// %s update object\n", funcName))
 			// func
 			buf.WriteString(fmt.Sprintf("func (d %sDao) %s", params.TableName, funcName))
-			buf.WriteString(fmt.Sprintf("(%s)", input))
+			buf.WriteString(fmt.Sprintf("(%s%s)", ctxInput, input))
 			buf.WriteString(" error {\n")
-			// filter  & update
-			filter += "	params := bson.M{}\n"
-			filter += "	for k, v := range fields{\n"
-			filter += "		params[k] = v\n"
-			filter += "	}\n"
-			filter += "	update := bson.M{\"$set\": params}\n"
+			if params.Tenant != nil {
+				buf.WriteString("	if err := d.checkTenant(ctx, update); err != nil {\n		return err\n	}\n")
+			}
+			// filter
 			buf.WriteString(filter)
+			buf.WriteString(scope)
 			// exp
-			buf.WriteString("	_, err := d.Collection().UpdateOne(context.Background(), filter, update)\n")
-			buf.WriteString("	return err\n")
+			if params.UpdatedBy != nil {
+				buf.WriteString("	update = d.audit(ctx, update)\n")
+			}
+			buf.WriteString(mongoRecord(params, "HistoryUpdate", fmt.Sprintf("d.Collection().UpdateOne(%s, filter, bson.M{\"$set\": update})", ctx)))
 			// quote
 			buf.WriteString("}\n\n")
 		}
@@ -223,27 +455,29 @@ func (mgo *mongodbGenerator) generateSelectIndexDao(params *commandParams, buf *
 				} else {
 					input += ", " + n + " " + vv.Type
 				}
-				name := strcase.ToSnakeWithIgnore(vv.Name, "sha1")
-				filter += fmt.Sprintf("		\"%s\": %s,\n", name, n)
+				filter += fmt.Sprintf("		\"%s\": %s,\n", vv.column, n)
 			}
 			filter += "	}\n"
-			if (!v.uniqueIndex && !v.normalIndex) || added[key] {
+			// 普通索引可能匹配多个对象, 由List方法查询
+			if !v.uniqueIndex || v.text || added[key] {
 				continue
 			}
 			added[key] = true
 
+			ctxInput, ctx, scope := mongoTenant(params, "nil, ")
 			funcName := fmt.Sprintf("Select%sBy%s", params.TableName, key)
 			// comments
 			buf.WriteString(fmt.Sprintf("// %s select object\n", funcName))
 			// func
 			buf.WriteString(fmt.Sprintf("func (d %sDao) %s", params.TableName, funcName))
-			buf.WriteString(fmt.Sprintf("(%s)", input))
+			buf.WriteString(fmt.Sprintf("(%s%s)", ctxInput, input))
 			buf.WriteString(fmt.Sprintf(" (*%sObj, error) {\n", params.TableName))
 			// filter
 			buf.WriteString(filter)
+			buf.WriteString(scope)
 			// exp
 			buf.WriteString(fmt.Sprintf("	obj := new(%sObj)\n", params.TableName))
-			buf.WriteString("	err := d.Collection().FindOne(context.Background(), filter).Decode(obj)\n")
+			buf.WriteString(fmt.Sprintf("	err := d.Collection().FindOne(%s, filter).Decode(obj)\n", ctx))
 			buf.WriteString("	return obj, err\n")
 			// quote
 			buf.WriteString("}\n\n")
@@ -251,15 +485,80 @@ func (mgo *mongodbGenerator) generateSelectIndexDao(params *commandParams, buf *
 	}
 }
 
-func (mgo *mongodbGenerator) generateModelFile(path string, list []*commandParams) error {
+func (mgo *mongodbGenerator) generateListIndexDao(params *commandParams, buf *bytes.Buffer) {
+	added := make(map[string]bool)
+	for _, v := range params.Fields {
+		for _, v := range v.indexs {
+			var (
+				key, input string
+				filter     = "	filter := bson.M{\n"
+			)
+			for i, vv := range v.indexFields {
+				key += vv.Name
+				n := strcase.ToLowerCamel(vv.Name)
+				if i == 0 {
+					input = n + " " + vv.Type
+				} else {
+					input += ", " + n + " " + vv.Type
+				}
+				filter += fmt.Sprintf("		\"%s\": %s,\n", vv.column, n)
+			}
+			filter += "	}\n"
+			if !v.normalIndex || v.text || added[key] {
+				continue
+			}
+			added[key] = true
+
+			// list
+			ctxInput, ctx, scope := mongoTenant(params, "nil, ")
+			funcName := fmt.Sprintf("List%sBy%s", params.TableName, key)
+			buf.WriteString(fmt.Sprintf("// %s list objects\n", funcName))
+			buf.WriteString(fmt.Sprintf("func (d %sDao) %s", params.TableName, funcName))
+			buf.WriteString(fmt.Sprintf("(%s%s, offset, limit int)", ctxInput, input))
+			buf.WriteString(fmt.Sprintf(" ([]*%sObj, error) {\n", params.TableName))
+			buf.WriteString(filter)
+			buf.WriteString(scope)
+			buf.WriteString("	opts := options.Find().SetSkip(int64(offset)).SetLimit(int64(limit))\n")
+			buf.WriteString(fmt.Sprintf("	cursor, err := d.Collection().Find(%s, filter, opts)\n", ctx))
+			buf.WriteString("	if err != nil {\n		return nil, err\n	}\n")
+			buf.WriteString(fmt.Sprintf("	var list []*%sObj\n", params.TableName))
+			buf.WriteString(fmt.Sprintf("	err = cursor.All(%s, &list)\n", ctx))
+			buf.WriteString("	return list, err\n")
+			buf.WriteString("}\n\n")
+
+			// count
+			funcName = fmt.Sprintf("Count%sBy%s", params.TableName, key)
+			buf.WriteString(fmt.Sprintf("// %s count objects\n", funcName))
+			buf.WriteString(fmt.Sprintf("func (d %sDao) %s", params.TableName, funcName))
+			_, _, scope = mongoTenant(params, "0, ")
+			buf.WriteString(fmt.Sprintf("(%s%s)", ctxInput, input))
+			buf.WriteString(" (int64, error) {\n")
+			buf.WriteString(filter)
+			buf.WriteString(scope)
+			buf.WriteString(fmt.Sprintf("	return d.Collection().CountDocuments(%s, filter)\n", ctx))
+			buf.WriteString("}\n\n")
+
+			// exists
+			funcName = fmt.Sprintf("Exists%sBy%s", params.TableName, key)
+			buf.WriteString(fmt.Sprintf("// %s whether object exists\n", funcName))
+			buf.WriteString(fmt.Sprintf("func (d %sDao) %s", params.TableName, funcName))
+			_, _, scope = mongoTenant(params, "false, ")
+			buf.WriteString(fmt.Sprintf("(%s%s)", ctxInput, input))
+			buf.WriteString(" (bool, error) {\n")
+			buf.WriteString(filter)
+			buf.WriteString(scope)
+			buf.WriteString(fmt.Sprintf("	count, err := d.Collection().CountDocuments(%s, filter, options.Count().SetLimit(1))\n", ctx))
+			buf.WriteString("	return count > 0, err\n")
+			buf.WriteString("}\n\n")
+		}
+	}
+}
+
+func (mgo *mongodbGenerator) generateModelFile(list []*commandParams) ([]byte, error) {
 	buf := &bytes.Buffer{}
 	err := ExecuteTemplate(buf, "modelMgoTmpl", list)
 	if err != nil {
-		return err
-	}
-	data, err := imports.Process("", buf.Bytes(), nil)
-	if err != nil {
-		return err
+		return nil, err
 	}
-	return os.WriteFile(path, data, 0644)
+	return imports.Process("", buf.Bytes(), nil)
 }
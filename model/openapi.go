@@ -0,0 +1,267 @@
+// Package model provides ...
+package model
+
+import (
+	"encoding/json"
+
+	"github.com/iancoleman/strcase"
+)
+
+type openAPIDoc struct {
+	OpenAPI    string                  `json:"openapi"`
+	Info       openAPIInfo             `json:"info"`
+	Paths      map[string]*openAPIPath `json:"paths"`
+	Components openAPIComponents       `json:"components"`
+}
+
+type openAPIInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type openAPIComponents struct {
+	Schemas map[string]*openAPISchema `json:"schemas"`
+}
+
+type openAPIPath struct {
+	Get    *openAPIOperation `json:"get,omitempty"`
+	Post   *openAPIOperation `json:"post,omitempty"`
+	Put    *openAPIOperation `json:"put,omitempty"`
+	Delete *openAPIOperation `json:"delete,omitempty"`
+}
+
+type openAPIOperation struct {
+	OperationID string                      `json:"operationId"`
+	Summary     string                      `json:"summary,omitempty"`
+	Tags        []string                    `json:"tags,omitempty"`
+	Parameters  []*openAPIParameter         `json:"parameters,omitempty"`
+	RequestBody *openAPIBody                `json:"requestBody,omitempty"`
+	Responses   map[string]*openAPIResponse `json:"responses"`
+}
+
+type openAPIParameter struct {
+	Name     string         `json:"name"`
+	In       string         `json:"in"`
+	Required bool           `json:"required"`
+	Schema   *openAPISchema `json:"schema"`
+}
+
+type openAPIBody struct {
+	Required bool                         `json:"required"`
+	Content  map[string]*openAPIMediaType `json:"content"`
+}
+
+type openAPIResponse struct {
+	Description string                       `json:"description"`
+	Content     map[string]*openAPIMediaType `json:"content,omitempty"`
+}
+
+type openAPIMediaType struct {
+	Schema *openAPISchema `json:"schema"`
+}
+
+type openAPISchema struct {
+	Ref         string                    `json:"$ref,omitempty"`
+	Type        string                    `json:"type,omitempty"`
+	Format      string                    `json:"format,omitempty"`
+	Description string                    `json:"description,omitempty"`
+	Nullable    bool                      `json:"nullable,omitempty"`
+	Enum        []string                  `json:"enum,omitempty"`
+	Items       *openAPISchema            `json:"items,omitempty"`
+	Properties  map[string]*openAPISchema `json:"properties,omitempty"`
+	Required    []string                  `json:"required,omitempty"`
+	AllOf       []*openAPISchema          `json:"allOf,omitempty"`
+}
+
+// goTypeToOpenAPI go type of sqlTypeToGo to openapi schema
+func goTypeToOpenAPI(goType string) *openAPISchema {
+	switch goType {
+	case "int":
+		return &openAPISchema{Type: "integer", Format: "int64"}
+	case "int64":
+		// json tag with ",string", see jsonString
+		return &openAPISchema{Type: "string", Format: "int64"}
+	case "int32":
+		return &openAPISchema{Type: "integer", Format: "int32"}
+	case "bool":
+		return &openAPISchema{Type: "boolean"}
+	case "string":
+		return &openAPISchema{Type: "string"}
+	case "primitive.ObjectID":
+		return &openAPISchema{Type: "string", Format: "objectid"}
+	case "[]byte":
+		return &openAPISchema{Type: "string", Format: "byte"}
+	case "time.Time", "gorm.DeletedAt":
+		return &openAPISchema{Type: "string", Format: "date-time"}
+	case "db.StringArray":
+		return &openAPISchema{Type: "array", Items: &openAPISchema{Type: "string"}}
+	case "db.Int64Array":
+		return &openAPISchema{Type: "array", Items: &openAPISchema{Type: "integer", Format: "int64"}}
+	}
+	// json.RawMessage etc. any value
+	return &openAPISchema{}
+}
+
+func openAPIRef(name string) *openAPISchema {
+	return &openAPISchema{Ref: "#/components/schemas/" + name}
+}
+
+// openAPIEnvelope ginhelper.ReturnClientDataForm with data
+func openAPIEnvelope(data *openAPISchema) *openAPISchema {
+	return &openAPISchema{
+		AllOf: []*openAPISchema{
+			openAPIRef("ReturnClientDataForm"),
+			{
+				Type:       "object",
+				Properties: map[string]*openAPISchema{"data": data},
+			},
+		},
+	}
+}
+
+func openAPIJSON(schema *openAPISchema) map[string]*openAPIMediaType {
+	return map[string]*openAPIMediaType{"application/json": {Schema: schema}}
+}
+
+func openAPIResponses(data *openAPISchema) map[string]*openAPIResponse {
+	return map[string]*openAPIResponse{
+		"200": {Description: "OK", Content: openAPIJSON(openAPIEnvelope(data))},
+		"400": {Description: "Bad Request", Content: openAPIJSON(openAPIRef("ReturnClientDataForm"))},
+	}
+}
+
+// generateOpenAPI generate openapi 3 document of schema, endpoints are same as handler
+func generateOpenAPI(schema *Schema, opts Options) ([]byte, error) {
+	doc := &openAPIDoc{
+		OpenAPI: "3.0.3",
+		Info:    openAPIInfo{Title: opts.PkgName + " API", Version: "1.0.0"},
+		Paths:   make(map[string]*openAPIPath),
+		Components: openAPIComponents{
+			Schemas: map[string]*openAPISchema{
+				"ReturnClientDataForm": {
+					Type: "object",
+					Properties: map[string]*openAPISchema{
+						"code": {Type: "integer", Description: "200 is OK, others are error code"},
+						"msg":  {Type: "string"},
+						"me":   {Type: "string"},
+						"data": {},
+					},
+					Required: []string{"code", "msg", "data"},
+				},
+			},
+		},
+	}
+
+	for _, t := range schema.writable() {
+		params := t.params
+		name := params.TableName
+		tag := strcase.ToSnake(name)
+
+		// object
+		obj := &openAPISchema{Type: "object", Description: params.Comment, Properties: make(map[string]*openAPISchema)}
+		for _, f := range params.Fields {
+			prop := goTypeToOpenAPI(f.Type)
+			prop.Description = f.Comment
+			prop.Nullable = !f.notNull
+			prop.Enum = f.enum
+			obj.Properties[strcase.ToSnake(f.Name)] = prop
+			if f.notNull {
+				obj.Required = append(obj.Required, strcase.ToSnake(f.Name))
+			}
+		}
+		doc.Components.Schemas[name] = obj
+
+		// request
+		create, update := handlerFields(params)
+		createReq := &openAPISchema{Type: "object", Properties: make(map[string]*openAPISchema)}
+		for _, v := range create {
+			prop := goTypeToOpenAPI(v.Type)
+			prop.Description = v.src.Comment
+			prop.Enum = v.src.enum
+			createReq.Properties[v.JSON] = prop
+			if v.Required {
+				createReq.Required = append(createReq.Required, v.JSON)
+			}
+		}
+		doc.Components.Schemas["Create"+name+"Req"] = createReq
+		updateReq := &openAPISchema{Type: "object", Properties: make(map[string]*openAPISchema)}
+		for _, v := range update {
+			prop := goTypeToOpenAPI(v.Type)
+			prop.Description = v.src.Comment
+			prop.Enum = v.src.enum
+			updateReq.Properties[v.JSON] = prop
+		}
+		doc.Components.Schemas["Update"+name+"Req"] = updateReq
+
+		list := &openAPISchema{
+			Type: "object",
+			Properties: map[string]*openAPISchema{
+				"total": {Type: "integer"},
+				"page":  {Type: "integer"},
+				"size":  {Type: "integer"},
+				"data":  {Type: "array", Items: openAPIRef(name)},
+			},
+			Required: []string{"total", "page", "size", "data"},
+		}
+
+		// paths
+		idType := "string"
+		if params.Primary != nil {
+			idType = params.Primary.Type
+		}
+		id := &openAPIParameter{Name: "id", In: "path", Required: true, Schema: goTypeToOpenAPI(idType)}
+		summary := params.Comment
+		if summary == "" {
+			summary = name
+		}
+		doc.Paths["/"+tag] = &openAPIPath{
+			Post: &openAPIOperation{
+				OperationID: "Create" + name,
+				Summary:     "create " + summary,
+				Tags:        []string{tag},
+				RequestBody: &openAPIBody{Required: true, Content: openAPIJSON(openAPIRef("Create" + name + "Req"))},
+				Responses:   openAPIResponses(openAPIRef(name)),
+			},
+			Get: &openAPIOperation{
+				OperationID: "List" + name,
+				Summary:     "list " + summary,
+				Tags:        []string{tag},
+				Parameters: []*openAPIParameter{
+					{Name: "page", In: "query", Required: true, Schema: &openAPISchema{Type: "integer"}},
+					{Name: "size", In: "query", Required: true, Schema: &openAPISchema{Type: "integer"}},
+				},
+				Responses: openAPIResponses(list),
+			},
+		}
+		doc.Paths["/"+tag+"/{id}"] = &openAPIPath{
+			Get: &openAPIOperation{
+				OperationID: "Select" + name,
+				Summary:     "select " + summary,
+				Tags:        []string{tag},
+				Parameters:  []*openAPIParameter{id},
+				Responses:   openAPIResponses(openAPIRef(name)),
+			},
+			Put: &openAPIOperation{
+				OperationID: "Update" + name,
+				Summary:     "update " + summary,
+				Tags:        []string{tag},
+				Parameters:  []*openAPIParameter{id},
+				RequestBody: &openAPIBody{Required: true, Content: openAPIJSON(openAPIRef("Update" + name + "Req"))},
+				Responses:   openAPIResponses(&openAPISchema{Type: "string"}),
+			},
+			Delete: &openAPIOperation{
+				OperationID: "Delete" + name,
+				Summary:     "delete " + summary,
+				Tags:        []string{tag},
+				Parameters:  []*openAPIParameter{id},
+				Responses:   openAPIResponses(&openAPISchema{Type: "string"}),
+			},
+		}
+	}
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
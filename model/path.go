@@ -16,7 +16,6 @@ type fileInfo struct {
 	path string // eg. cmd/example/model/example/xx.sql
 
 	dst string // eg. <dst>/example
-	pkg string // eg. <dst>/example
 }
 
 func calculatePath(src, dst string) ([]fileInfo, error) {
@@ -40,14 +39,11 @@ func calculatePath(src, dst string) ([]fileInfo, error) {
 						return err
 					}
 				}
-
-				pkg := getPkgName(dst)
 				files = append(files, fileInfo{
 					name: strings.Replace(name, ".sql", "", 1),
 					file: name,
 					path: path,
 					dst:  filepath.Join(dst, folder),
-					pkg:  pkg,
 				})
 			}
 			return nil
@@ -5,7 +5,8 @@ import (
 	"bytes"
 	_ "embed" // embed
 	"fmt"
-	"os"
+	"regexp"
+	"strings"
 
 	"github.com/iancoleman/strcase"
 	"golang.org/x/tools/imports"
@@ -14,6 +15,9 @@ import (
 //go:embed template/internal_pg.tmpl
 var internalPGTmpl string
 
+//go:embed template/internal_pg_view.tmpl
+var internalPGViewTmpl string
+
 //go:embed template/custom_pg.tmpl
 var customPGTmpl string
 
@@ -28,6 +32,10 @@ func newPostgresGenerator() (*postgresGenerator, error) {
 	if err != nil {
 		return nil, err
 	}
+	err = ParseTemplate("internalPGViewTmpl", internalPGViewTmpl)
+	if err != nil {
+		return nil, err
+	}
 	err = ParseTemplate("customPGTmpl", customPGTmpl)
 	if err != nil {
 		return nil, err
@@ -39,89 +47,144 @@ func newPostgresGenerator() (*postgresGenerator, error) {
 	return &postgresGenerator{}, nil
 }
 
-func (pg *postgresGenerator) generateInternalFile(path string, params *commandParams) error {
+func (pg *postgresGenerator) generateInternalFile(params *commandParams) ([]byte, error) {
 	buf := new(bytes.Buffer)
-	pg.generateDeleteIndexDao(params, buf)
-	pg.generateUpdateIndexDao(params, buf)
+	name := "internalPGTmpl"
+	if params.View {
+		// 视图只读
+		name = "internalPGViewTmpl"
+		params.Concurrently = pgConcurrently(params)
+	} else {
+		pg.generateDeleteIndexDao(params, buf)
+		pg.generateUpdateIndexDao(params, buf)
+	}
 	pg.generateSelectIndexDao(params, buf)
+	pg.generateListIndexDao(params, buf)
 	params.IndexGo = buf.String()
 
 	buf.Reset()
-	err := ExecuteTemplate(buf, "internalPGTmpl", params)
+	err := ExecuteTemplate(buf, name, params)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	// imports
 	data, err := imports.Process("", buf.Bytes(), nil)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	return os.WriteFile(path, data, 0644)
+	return appendRepository(data, params.TableName)
 }
 
-func (pg *postgresGenerator) generateCustomFile(path string, params *commandParams) error {
+func (pg *postgresGenerator) generateCustomFile(params *commandParams) ([]byte, error) {
 	buf := &bytes.Buffer{}
 	err := ExecuteTemplate(buf, "customPGTmpl", params)
 	if err != nil {
-		return err
-	}
-	data, err := imports.Process("", buf.Bytes(), nil)
-	if err != nil {
-		return err
+		return nil, err
 	}
-	return os.WriteFile(path, data, 0644)
+	return imports.Process("", buf.Bytes(), nil)
 }
 
-func (pg *postgresGenerator) generateDeleteIndexDao(params *commandParams, buf *bytes.Buffer) {
+// pgIndexes 按字段及索引定义顺序收集可生成DAO方法的唯一索引或普通索引
+func pgIndexes(params *commandParams, unique bool) []index {
+	var list []index
 	added := make(map[string]bool)
-
-	// 收集所有唯一索引
-	uniqueIndexes := make(map[string]index)
 	for _, v := range params.Fields {
 		for _, idx := range v.indexs {
-			if idx.uniqueIndex && idx.indexName != "" {
-				uniqueIndexes[idx.indexName] = idx
+			if idx.uniqueIndex != unique || idx.indexName == "" || idx.multiColumnExpr || added[idx.indexName] {
+				continue
+			}
+			if !unique && !idx.normalIndex {
+				continue
 			}
+			added[idx.indexName] = true
+			list = append(list, idx)
 		}
 	}
+	return list
+}
 
-	// 为每个唯一索引生成删除方法
-	for indexName, idx := range uniqueIndexes {
-		var (
-			key, input string
-			w, q       string
-		)
-		for i, vv := range idx.indexFields {
-			key += vv.Name
-
-			n := strcase.ToLowerCamel(vv.Name)
-			name := strcase.ToSnakeWithIgnore(vv.Name, "sha1")
-			if i == 0 {
-				input = n + " " + vv.Type
-				w = name + "=?"
-				q = n
-			} else {
-				input += ", " + n + " " + vv.Type
-				w += " AND " + name + "=?"
-				q += ", " + n
-			}
+// pgConcurrently unique index of materialized view for REFRESH CONCURRENTLY, which must be on columns without condition
+func pgConcurrently(params *commandParams) string {
+	if !params.Materialized {
+		return ""
+	}
+	for _, idx := range pgIndexes(params, true) {
+		if idx.where != "" || strings.Join(idx.exprs, "") != "" {
+			continue
 		}
+		return idx.indexName
+	}
+	return ""
+}
 
+// pgIndexArgs method key, input params, where condition and its args of index,
+// eg. EmailAge, "email string, age int", "email=? AND age=?", "email, age"
+func pgIndexArgs(idx index) (key, input, w, q string) {
+	for i, vv := range idx.indexFields {
+		key += vv.Name
+		n := strcase.ToLowerCamel(vv.Name)
+		if i == 0 {
+			input = n + " " + vv.Type
+			w = pgIndexCond(idx, i)
+			q = n
+		} else {
+			input += ", " + n + " " + vv.Type
+			w += " AND " + pgIndexCond(idx, i)
+			q += ", " + n
+		}
+	}
+	if idx.where != "" {
+		// 部分索引只包含条件内的数据
+		w += " AND (" + idx.where + ")"
+	}
+	return
+}
+
+// pgTenant ctx param and db of dao method, db is scoped by tenant of ctx for table with tenant column
+func pgTenant(params *commandParams) (ctx, db string) {
+	switch {
+	case params.Tenant != nil:
+		return "ctx context.Context, ", "d.scope(ctx)"
+	case params.Context:
+		return "ctx context.Context, ", "d.DB.WithContext(ctx)"
+	}
+	return "", "d.DB"
+}
+
+// pgRecord write statement of update/delete by where condition, changes are recorded to history if enabled
+func pgRecord(params *commandParams, db, action, model, w, q, write string) string {
+	if !params.History {
+		return fmt.Sprintf("	return %s%s.Where(%q, %s)%s\n", db, model, w, q, write)
+	}
+	return fmt.Sprintf(`	return d.record(ctx, db.%s, func(tx *gorm.DB) *gorm.DB {
+		return tx.Where(%q, %s)
+	}, func(tx *gorm.DB) error {
+		return tx%s%s
+	})
+`, action, w, q, model, write)
+}
+
+func (pg *postgresGenerator) generateDeleteIndexDao(params *commandParams, buf *bytes.Buffer) {
+	added := make(map[string]bool)
+
+	// 为每个唯一索引生成删除方法
+	for _, idx := range pgIndexes(params, true) {
+		key, input, w, q := pgIndexArgs(idx)
 		if added[key] {
 			continue
 		}
 		added[key] = true
+		ctx, db := pgTenant(params)
 
 		funcName := fmt.Sprintf("Delete%sBy%s", params.TableName, key)
 		// comments
-		buf.WriteString(fmt.Sprintf("// %s delete object by unique index %s\n", funcName, indexName))
+		buf.WriteString(fmt.Sprintf("// %s delete object by unique index %s\n", funcName, idx.indexName))
 		// func
 		buf.WriteString(fmt.Sprintf("func (d %sDao) %s", params.TableName, funcName))
-		buf.WriteString(fmt.Sprintf("(%s)", input))
+		buf.WriteString(fmt.Sprintf("(%s%s)", ctx, input))
 		buf.WriteString(" error {\n")
 		// exp
-		buf.WriteString(fmt.Sprintf(`	return d.DB.Where("%s", %s)`, w, q))
-		buf.WriteString(fmt.Sprintf(".Delete(%sObj{}).Error\n", params.TableName))
+		buf.WriteString(pgRecord(params, db, "HistoryDelete", "", w, q, fmt.Sprintf(".Delete(%sObj{}).Error", params.TableName)))
 		// quote
 		buf.WriteString("}\n\n")
 	}
@@ -130,55 +193,33 @@ func (pg *postgresGenerator) generateDeleteIndexDao(params *commandParams, buf *
 func (pg *postgresGenerator) generateUpdateIndexDao(params *commandParams, buf *bytes.Buffer) {
 	added := make(map[string]bool)
 
-	// 收集所有唯一索引
-	uniqueIndexes := make(map[string]index)
-	for _, v := range params.Fields {
-		for _, idx := range v.indexs {
-			if idx.uniqueIndex && idx.indexName != "" {
-				uniqueIndexes[idx.indexName] = idx
-			}
-		}
-	}
-
 	// 为每个唯一索引生成更新方法
-	for indexName, idx := range uniqueIndexes {
-		var (
-			key, input string
-			w, q       string
-		)
-		for i, vv := range idx.indexFields {
-			key += vv.Name
-			n := strcase.ToLowerCamel(vv.Name)
-			name := strcase.ToSnakeWithIgnore(vv.Name, "sha1")
-			if i == 0 {
-				input = n + " " + vv.Type
-				w = name + "=?"
-				q = n
-			} else {
-				input += ", " + n + " " + vv.Type
-				w += " AND " + name + "=?"
-				q += ", " + n
-			}
-		}
-
+	for _, idx := range pgIndexes(params, true) {
+		key, input, w, q := pgIndexArgs(idx)
 		if added[key] {
 			continue
 		}
 		added[key] = true
+		ctx, db := pgTenant(params)
 
 		input += ", fields map[string]interface{}"
 
 		funcName := fmt.Sprintf("Update%sBy%s", params.TableName, key)
 		// comments
-		buf.WriteString(fmt.Sprintf("// %s update object by unique index %s\n", funcName, indexName))
+		buf.WriteString(fmt.Sprintf("// %s update object by unique index %s\n", funcName, idx.indexName))
 		// func
 		buf.WriteString(fmt.Sprintf("func (d %sDao) %s", params.TableName, funcName))
-		buf.WriteString(fmt.Sprintf("(%s)", input))
+		buf.WriteString(fmt.Sprintf("(%s%s)", ctx, input))
 		buf.WriteString(" error {\n")
 		// exp
-		buf.WriteString(fmt.Sprintf(`	return d.DB.Model(%sObj{}).Where("%s", %s)`,
-			params.TableName, w, q))
-		buf.WriteString(".Updates(fields).Error\n")
+		if params.Tenant != nil {
+			buf.WriteString("	err := d.checkTenant(ctx, fields)\n")
+			buf.WriteString("	if err != nil {\n		return err\n	}\n")
+		}
+		if params.UpdatedBy != nil {
+			buf.WriteString("	fields = d.audit(ctx, fields)\n")
+		}
+		buf.WriteString(pgRecord(params, db, "HistoryUpdate", fmt.Sprintf(".Model(%sObj{})", params.TableName), w, q, ".Updates(fields).Error"))
 		// quote
 		buf.WriteString("}\n\n")
 	}
@@ -187,52 +228,25 @@ func (pg *postgresGenerator) generateUpdateIndexDao(params *commandParams, buf *
 func (pg *postgresGenerator) generateSelectIndexDao(params *commandParams, buf *bytes.Buffer) {
 	added := make(map[string]bool)
 
-	// 收集所有唯一索引
-	uniqueIndexes := make(map[string]index)
-	for _, v := range params.Fields {
-		for _, idx := range v.indexs {
-			if idx.uniqueIndex && idx.indexName != "" {
-				uniqueIndexes[idx.indexName] = idx
-			}
-		}
-	}
-
 	// 为每个唯一索引生成查询方法
-	for indexName, idx := range uniqueIndexes {
-		var (
-			key, input string
-			w, q       string
-		)
-		for i, vv := range idx.indexFields {
-			key += vv.Name
-			n := strcase.ToLowerCamel(vv.Name)
-			name := strcase.ToSnakeWithIgnore(vv.Name, "sha1")
-			if i == 0 {
-				input = n + " " + vv.Type
-				w = name + "=?"
-				q = n
-			} else {
-				input += ", " + n + " " + vv.Type
-				w += " AND " + name + "=?"
-				q += ", " + n
-			}
-		}
-
+	for _, idx := range pgIndexes(params, true) {
+		key, input, w, q := pgIndexArgs(idx)
 		if added[key] {
 			continue
 		}
 		added[key] = true
+		ctx, db := pgTenant(params)
 
 		funcName := fmt.Sprintf("Select%sBy%s", params.TableName, key)
 		// comments
-		buf.WriteString(fmt.Sprintf("// %s select object by unique index %s\n", funcName, indexName))
+		buf.WriteString(fmt.Sprintf("// %s select object by unique index %s\n", funcName, idx.indexName))
 		// func
 		buf.WriteString(fmt.Sprintf("func (d %sDao) %s", params.TableName, funcName))
-		buf.WriteString(fmt.Sprintf("(%s)", input))
+		buf.WriteString(fmt.Sprintf("(%s%s)", ctx, input))
 		buf.WriteString(fmt.Sprintf(" (*%sObj, error) {\n", params.TableName))
 		// exp
 		buf.WriteString(fmt.Sprintf("	obj := new(%sObj)\n", params.TableName))
-		buf.WriteString(fmt.Sprintf(`	err := d.DB.Where("%s", %s)`, w, q))
+		buf.WriteString(fmt.Sprintf(`	err := %s.Where(%q, %s)`, db, w, q))
 		buf.WriteString(".First(obj).Error\n")
 		buf.WriteString("	return obj, err\n")
 		// quote
@@ -240,15 +254,82 @@ func (pg *postgresGenerator) generateSelectIndexDao(params *commandParams, buf *
 	}
 }
 
-func (pg *postgresGenerator) generateModelFile(path string, list []*commandParams) error {
+func (pg *postgresGenerator) generateListIndexDao(params *commandParams, buf *bytes.Buffer) {
+	added := make(map[string]bool)
+
+	// 为每个普通索引生成列表、计数及存在查询方法
+	for _, idx := range pgIndexes(params, false) {
+		key, input, w, q := pgIndexArgs(idx)
+		if added[key] {
+			continue
+		}
+		added[key] = true
+		ctx, db := pgTenant(params)
+
+		// list
+		funcName := fmt.Sprintf("List%sBy%s", params.TableName, key)
+		buf.WriteString(fmt.Sprintf("// %s list objects by index %s\n", funcName, idx.indexName))
+		buf.WriteString(fmt.Sprintf("func (d %sDao) %s", params.TableName, funcName))
+		buf.WriteString(fmt.Sprintf("(%s%s, offset, limit int)", ctx, input))
+		buf.WriteString(fmt.Sprintf(" ([]*%sObj, error) {\n", params.TableName))
+		buf.WriteString(fmt.Sprintf("	var list []*%sObj\n", params.TableName))
+		buf.WriteString(fmt.Sprintf(`	err := %s.Where(%q, %s)`, db, w, q))
+		buf.WriteString(".Offset(offset).Limit(limit).Find(&list).Error\n")
+		buf.WriteString("	return list, err\n")
+		buf.WriteString("}\n\n")
+
+		// count
+		funcName = fmt.Sprintf("Count%sBy%s", params.TableName, key)
+		buf.WriteString(fmt.Sprintf("// %s count objects by index %s\n", funcName, idx.indexName))
+		buf.WriteString(fmt.Sprintf("func (d %sDao) %s", params.TableName, funcName))
+		buf.WriteString(fmt.Sprintf("(%s%s)", ctx, input))
+		buf.WriteString(" (int64, error) {\n")
+		buf.WriteString("	var count int64\n")
+		buf.WriteString(fmt.Sprintf(`	err := %s.Model(%sObj{}).Where(%q, %s)`, db, params.TableName, w, q))
+		buf.WriteString(".Count(&count).Error\n")
+		buf.WriteString("	return count, err\n")
+		buf.WriteString("}\n\n")
+
+		// exists
+		funcName = fmt.Sprintf("Exists%sBy%s", params.TableName, key)
+		buf.WriteString(fmt.Sprintf("// %s whether object exists by index %s\n", funcName, idx.indexName))
+		buf.WriteString(fmt.Sprintf("func (d %sDao) %s", params.TableName, funcName))
+		buf.WriteString(fmt.Sprintf("(%s%s)", ctx, input))
+		buf.WriteString(" (bool, error) {\n")
+		buf.WriteString("	var exists bool\n")
+		if params.Tenant != nil {
+			// 子查询及外层查询使用同一个scope, 以返回缺少租户的错误
+			buf.WriteString("	tx := d.scope(ctx)\n")
+			db = "tx"
+		} else if params.Context {
+			buf.WriteString("	tx := d.DB.WithContext(ctx)\n")
+			db = "tx"
+		} else {
+			db = "d.DB"
+		}
+		buf.WriteString(fmt.Sprintf(`	err := %s.Raw("SELECT EXISTS (?)", %s.Model(%sObj{}).Select("1").Where(%q, %s))`,
+			db, db, params.TableName, w, q))
+		buf.WriteString(".Scan(&exists).Error\n")
+		buf.WriteString("	return exists, err\n")
+		buf.WriteString("}\n\n")
+	}
+}
+
+// pgIndexCond where condition of index field, eg. email=? or lower(email)=lower(?)
+func pgIndexCond(idx index, i int) string {
+	f := idx.indexFields[i]
+	if idx.exprs == nil || idx.exprs[i] == "" {
+		return f.column + "=?"
+	}
+	column := regexp.MustCompile(`"?\b` + regexp.QuoteMeta(f.column) + `\b"?`)
+	return idx.exprs[i] + "=" + column.ReplaceAllString(idx.exprs[i], "?")
+}
+
+func (pg *postgresGenerator) generateModelFile(list []*commandParams) ([]byte, error) {
 	buf := &bytes.Buffer{}
 	err := ExecuteTemplate(buf, "modelPGTmpl", list)
 	if err != nil {
-		return err
-	}
-	data, err := imports.Process("", buf.Bytes(), nil)
-	if err != nil {
-		return err
+		return nil, err
 	}
-	return os.WriteFile(path, data, 0644)
+	return imports.Process("", buf.Bytes(), nil)
 }
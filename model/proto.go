@@ -0,0 +1,228 @@
+// Package model provides ...
+package model
+
+import (
+	"bytes"
+	_ "embed" // embed
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/iancoleman/strcase"
+	"golang.org/x/tools/imports"
+)
+
+//go:embed template/proto.tmpl
+var protoTmpl string
+
+//go:embed template/proto_convert.tmpl
+var protoConvertTmpl string
+
+//go:embed template/proto_helper.tmpl
+var protoHelperTmpl string
+
+type protoTable struct {
+	Name        string // eg. User
+	Comment     string
+	ObjType     string // eg. model.ObjUser
+	ModelImport string
+	Import      string
+	UpdateType  string // mongodb typed update, eg. model.UpdateUser
+	Fallible    bool   // 有字段转换可能失败, ToObj/UpdateFields返回error
+
+	Fields []*protoField
+	Update []*protoField // 可更新字段, 不含主键
+	Keys   []*protoKey   // 主键及唯一索引
+}
+
+type protoField struct {
+	Name      string // eg. tenant_id
+	GoName    string // protoc-gen-go字段名, eg. Id
+	ObjName   string // Obj字段名, eg. ID
+	Column    string
+	Type      string // eg. repeated string
+	Number    int
+	Comment   string
+	ToProto   string // Obj字段转proto, eg. int64(obj.ID)
+	FromProto string // proto字段转Obj, eg. int(msg.GetId())
+	Fallible  bool   // FromProto返回(值, error), eg. objectIDFromProto
+}
+
+type protoKey struct {
+	Name    string // eg. ByEmailAge
+	Message string // eg. UserByEmailAge
+	Comment string
+	Primary bool
+	Fields  []*protoField
+}
+
+// protoGoName field name generated by protoc-gen-go
+func protoGoName(name string) string {
+	parts := strings.Split(name, "_")
+	for i, v := range parts {
+		if v != "" {
+			parts[i] = strings.ToUpper(v[:1]) + v[1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+// newProtoField proto field of go type, types are from sqlTypeToGo
+func newProtoField(f *field, number int) *protoField {
+	name := strcase.ToSnake(f.Name)
+	pf := &protoField{
+		Name:    name,
+		GoName:  protoGoName(name),
+		ObjName: f.Name,
+		Column:  f.column,
+		Number:  number,
+		Comment: f.Comment,
+	}
+	obj, msg := "obj."+f.Name, "msg.Get"+pf.GoName+"()"
+	switch f.Type {
+	case "int":
+		pf.Type, pf.ToProto, pf.FromProto = "int64", "int64("+obj+")", "int("+msg+")"
+	case "int32", "int64", "bool", "string":
+		pf.Type, pf.ToProto, pf.FromProto = f.Type, obj, msg
+	case "[]byte":
+		pf.Type, pf.ToProto, pf.FromProto = "bytes", obj, msg
+	case "time.Time":
+		pf.Type, pf.ToProto, pf.FromProto = "google.protobuf.Timestamp", "timeToProto("+obj+")", "timeFromProto("+msg+")"
+	case "gorm.DeletedAt":
+		pf.Type, pf.ToProto, pf.FromProto = "google.protobuf.Timestamp", "deletedAtToProto("+obj+")", "deletedAtFromProto("+msg+")"
+	case "json.RawMessage":
+		pf.Type, pf.ToProto, pf.FromProto = "google.protobuf.Value", "jsonToProto("+obj+")", "jsonFromProto("+msg+")"
+	case "db.StringArray":
+		pf.Type, pf.ToProto, pf.FromProto = "repeated string", "[]string("+obj+")", "db.StringArray("+msg+")"
+	case "db.Int64Array":
+		pf.Type, pf.ToProto, pf.FromProto = "repeated int64", "[]int64("+obj+")", "db.Int64Array("+msg+")"
+	case "primitive.ObjectID":
+		pf.Type, pf.ToProto, pf.FromProto = "string", obj+".Hex()", "objectIDFromProto("+msg+")"
+		pf.Fallible = true
+	default:
+		pf.Type, pf.ToProto, pf.FromProto = f.Type, obj, msg
+	}
+	return pf
+}
+
+// generateProto generate proto3 messages, CRUD services and converters of Obj
+func generateProto(schema *Schema, opts Options, files map[string][]byte) error {
+	for name, text := range map[string]string{
+		"protoTmpl":        protoTmpl,
+		"protoConvertTmpl": protoConvertTmpl,
+		"protoHelperTmpl":  protoHelperTmpl,
+	} {
+		err := ParseTemplate(name, text)
+		if err != nil {
+			return err
+		}
+	}
+
+	var timestamp, structpb, deletedAt, objectID bool
+	tables := schema.writable()
+	list := make([]*protoTable, len(tables))
+	for i, t := range tables {
+		params := t.params
+		pt := &protoTable{
+			Name:        params.TableName,
+			Comment:     params.Comment,
+			ObjType:     objType(params),
+			ModelImport: opts.ImportPath,
+			Import:      params.Import,
+		}
+		if schema.Dialect == MongoDB {
+			pt.UpdateType = strings.Replace(pt.ObjType, ".Obj", ".Update", 1)
+		}
+		fields := make(map[*field]*protoField)
+		for j, f := range params.Fields {
+			pf := newProtoField(f, j+1)
+			fields[f] = pf
+			pt.Fields = append(pt.Fields, pf)
+			if params.Primary == nil || f != params.Primary.field {
+				pt.Update = append(pt.Update, pf)
+			}
+
+			timestamp = timestamp || pf.Type == "google.protobuf.Timestamp"
+			structpb = structpb || pf.Type == "google.protobuf.Value"
+			deletedAt = deletedAt || f.Type == "gorm.DeletedAt"
+			objectID = objectID || f.Type == "primitive.ObjectID"
+			pt.Fallible = pt.Fallible || pf.Fallible
+		}
+
+		// primary key
+		key := &protoKey{Message: params.TableName + "Key", Comment: "primary key", Primary: true}
+		if params.Primary == nil {
+			key.Fields = []*protoField{{Name: "id", Type: "string", Number: 1}}
+		} else {
+			pf := *fields[params.Primary.field]
+			pf.Number = 1
+			key.Fields = []*protoField{&pf}
+		}
+		pt.Keys = append(pt.Keys, key)
+
+		// unique index
+		added := make(map[string]bool)
+		for _, f := range params.Fields {
+			for _, idx := range f.indexs {
+				if !idx.uniqueIndex {
+					continue
+				}
+				key := &protoKey{Comment: "unique index " + idx.indexName}
+				for j, v := range idx.indexFields {
+					key.Name += v.Name
+					pf := *fields[v]
+					pf.Number = j + 1
+					key.Fields = append(key.Fields, &pf)
+				}
+				if added[key.Name] {
+					continue
+				}
+				added[key.Name] = true
+				key.Name = "By" + key.Name
+				key.Message = params.TableName + key.Name
+				pt.Keys = append(pt.Keys, key)
+			}
+		}
+		list[i] = pt
+
+		// converter
+		buf := new(bytes.Buffer)
+		err := ExecuteTemplate(buf, "protoConvertTmpl", pt)
+		if err != nil {
+			return err
+		}
+		data, err := imports.Process("", buf.Bytes(), nil)
+		if err != nil {
+			return err
+		}
+		files[filepath.Join("proto", t.File+"_convert.go")] = data
+	}
+
+	buf := new(bytes.Buffer)
+	err := ExecuteTemplate(buf, "protoTmpl", map[string]interface{}{
+		"Package":   opts.PkgName,
+		"GoPackage": path.Join(opts.ImportPath, "proto") + ";pb",
+		"Timestamp": timestamp,
+		"Struct":    structpb,
+		"List":      list,
+	})
+	if err != nil {
+		return err
+	}
+	files[filepath.Join("proto", opts.PkgName+".proto")] = append([]byte(nil), buf.Bytes()...)
+
+	buf.Reset()
+	err = ExecuteTemplate(buf, "protoHelperTmpl", map[string]interface{}{
+		"DeletedAt": deletedAt,
+		"ObjectID":  objectID,
+	})
+	if err != nil {
+		return err
+	}
+	data, err := imports.Process("", buf.Bytes(), nil)
+	if err != nil {
+		return err
+	}
+	files[filepath.Join("proto", "convert.go")] = data
+	return nil
+}
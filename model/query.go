@@ -0,0 +1,43 @@
+// Package model provides ...
+package model
+
+import (
+	"strings"
+)
+
+type queryColumn struct {
+	Name   string // 字段名, eg. Email
+	Type   string // eg. string
+	Column string // postgres列名或mongodb字段名, eg. email, _id
+}
+
+// queryTable set typed columns of params for query builder, eg. Users.Email
+func queryTable(params *commandParams, dialect Dialect) {
+	params.QueryVar = plural(params.TableName)
+	params.QueryColumns = nil
+	for _, f := range params.Fields {
+		c := &queryColumn{Name: f.Name, Type: f.Type, Column: f.column}
+		if dialect == MongoDB {
+			c.Column = strings.TrimSuffix(f.Bson, ",omitempty")
+		}
+		// 与<Table>Columns的Query方法同名
+		if c.Name == "Query" {
+			c.Name += "Column"
+		}
+		params.QueryColumns = append(params.QueryColumns, c)
+	}
+}
+
+// plural english plural of name, eg. User -> Users, Category -> Categories
+func plural(name string) string {
+	lower := strings.ToLower(name)
+	for _, suffix := range []string{"s", "x", "z", "ch", "sh"} {
+		if strings.HasSuffix(lower, suffix) {
+			return name + "es"
+		}
+	}
+	if n := len(lower); n > 1 && lower[n-1] == 'y' && !strings.ContainsRune("aeiou", rune(lower[n-2])) {
+		return name[:n-1] + "ies"
+	}
+	return name + "s"
+}
@@ -0,0 +1,443 @@
+// Package model provides ...
+package model
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/iancoleman/strcase"
+	"github.com/urfave/cli/v2"
+	"golang.org/x/tools/go/packages"
+)
+
+// reverseCommand generate DDL by gorm struct
+var reverseCommand = &cli.Command{
+	Name:  "reverse",
+	Usage: "to generate postgres DDL by gorm struct",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "pkg",
+			Usage: "Go package pattern, eg. ./cmd/ta-auth/model/...",
+			Value: ".",
+		},
+		&cli.StringFlag{
+			Name:  "dst",
+			Usage: "DDL file dest dir, print to stdout if empty",
+		},
+	},
+	Action: reverseAction,
+}
+
+func reverseAction(c *cli.Context) error {
+	files, err := Reverse("", strings.Split(c.String("pkg"), ",")...)
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	dst := c.String("dst")
+	for _, name := range names {
+		if dst == "" {
+			fmt.Printf("-- %s\n%s\n", name, files[name])
+			continue
+		}
+		_ = os.MkdirAll(dst, 0755)
+		err = os.WriteFile(filepath.Join(dst, name), files[name], 0644)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// goTypeToSQL go type to postgres type, reverse of sqlTypeToGo
+var goTypeToSQL = map[string]string{
+	"int":             "INTEGER",
+	"int32":           "SMALLINT",
+	"int64":           "BIGINT",
+	"bool":            "BOOLEAN",
+	"string":          "TEXT",
+	"[]byte":          "BYTEA",
+	"time.Time":       "TIMESTAMP",
+	"gorm.DeletedAt":  "TIMESTAMP",
+	"json.RawMessage": "JSONB",
+	"db.StringArray":  "TEXT[]",
+	"db.Int64Array":   "INTEGER[]",
+}
+
+// Reverse load go packages and generate postgres DDL of gorm struct,
+// returns DDL by file name, eg. user.sql
+func Reverse(dir string, patterns ...string) (map[string][]byte, error) {
+	cfg := &packages.Config{
+		Mode: packages.NeedName | packages.NeedFiles | packages.NeedSyntax,
+		Dir:  dir,
+	}
+	pkgs, err := packages.Load(cfg, patterns...)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string][]byte)
+	for _, pkg := range pkgs {
+		if len(pkg.Syntax) == 0 && len(pkg.Errors) > 0 {
+			return nil, pkg.Errors[0]
+		}
+		tableNames := reverseTableNames(pkg.Syntax)
+		for _, file := range pkg.Syntax {
+			for _, decl := range file.Decls {
+				gen, ok := decl.(*ast.GenDecl)
+				if !ok || gen.Tok != token.TYPE {
+					continue
+				}
+				for _, spec := range gen.Specs {
+					ts := spec.(*ast.TypeSpec)
+					st, ok := ts.Type.(*ast.StructType)
+					if !ok || !isGormStruct(st) {
+						continue
+					}
+					name, ok := tableNames[ts.Name.Name]
+					if !ok {
+						name = strcase.ToSnake(strings.TrimSuffix(ts.Name.Name, "Obj"))
+					}
+					doc := ts.Doc
+					if doc == nil && len(gen.Specs) == 1 {
+						doc = gen.Doc
+					}
+					data, err := reverseTable(name, reverseComment(ts.Name.Name, doc), st)
+					if err != nil {
+						return nil, fmt.Errorf("%s.%s: %w", pkg.PkgPath, ts.Name.Name, err)
+					}
+					files[name+".sql"] = data
+				}
+			}
+		}
+	}
+	return files, nil
+}
+
+// reverseTableNames found `func (T) TableName() string { return "xx" }`
+func reverseTableNames(files []*ast.File) map[string]string {
+	names := make(map[string]string)
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Name.Name != "TableName" || fn.Recv == nil || fn.Body == nil {
+				continue
+			}
+			if len(fn.Body.List) != 1 {
+				continue
+			}
+			ret, ok := fn.Body.List[0].(*ast.ReturnStmt)
+			if !ok || len(ret.Results) != 1 {
+				continue
+			}
+			lit, ok := ret.Results[0].(*ast.BasicLit)
+			if !ok || lit.Kind != token.STRING {
+				continue
+			}
+			recv := fn.Recv.List[0].Type
+			if star, ok := recv.(*ast.StarExpr); ok {
+				recv = star.X
+			}
+			if ident, ok := recv.(*ast.Ident); ok {
+				names[ident.Name], _ = strconv.Unquote(lit.Value)
+			}
+		}
+	}
+	return names
+}
+
+func isGormStruct(st *ast.StructType) bool {
+	for _, f := range st.Fields.List {
+		if f.Tag == nil {
+			continue
+		}
+		tag, _ := strconv.Unquote(f.Tag.Value)
+		if strings.Contains(reflect.StructTag(tag).Get("gorm"), "column:") {
+			return true
+		}
+	}
+	return false
+}
+
+type reverseColumn struct {
+	name       string
+	sqlType    string
+	defaultVal string
+	notNull    bool
+	comment    string
+}
+
+type reverseIndex struct {
+	name   string
+	unique bool
+	using  string // type:gin
+	where  string
+	option string // option:CONCURRENTLY
+	elems  []*reverseIndexElem
+}
+
+type reverseIndexElem struct {
+	column     string
+	expression string
+	sort       string
+	priority   int
+}
+
+// parseIndexTag parse value of gorm index tag, eg. idx_user_email,type:btree,where:deleted_at IS NULL,sort:desc,priority:2
+func parseIndexTag(idx *reverseIndex, elem *reverseIndexElem, options []string) error {
+	elem.priority = 10 // gorm默认值
+	for _, v := range options {
+		key, val, _ := strings.Cut(v, ":")
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "unique":
+			idx.unique = true
+		case "type":
+			idx.using = val
+		case "where":
+			idx.where = val
+		case "option":
+			idx.option = strings.ToUpper(val)
+		case "expression":
+			elem.expression = val
+		case "sort":
+			elem.sort = strings.ToUpper(val)
+		case "priority":
+			n, err := strconv.Atoi(val)
+			if err != nil {
+				return fmt.Errorf("invalid priority of index %s: %s", idx.name, val)
+			}
+			elem.priority = n
+		}
+	}
+	return nil
+}
+
+// indexSQL CREATE INDEX of idx, elements are ordered by priority
+func (idx *reverseIndex) indexSQL(table string) string {
+	sort.SliceStable(idx.elems, func(i, j int) bool {
+		return idx.elems[i].priority < idx.elems[j].priority
+	})
+	elems := make([]string, len(idx.elems))
+	for i, v := range idx.elems {
+		elems[i] = v.column
+		if v.expression != "" {
+			elems[i] = "(" + v.expression + ")"
+		}
+		if v.sort != "" {
+			elems[i] += " " + v.sort
+		}
+	}
+
+	buf := new(strings.Builder)
+	buf.WriteString("CREATE ")
+	if idx.unique {
+		buf.WriteString("UNIQUE ")
+	}
+	buf.WriteString("INDEX ")
+	if idx.option == "CONCURRENTLY" {
+		buf.WriteString("CONCURRENTLY ")
+	}
+	buf.WriteString(fmt.Sprintf("%s ON \"%s\" ", idx.name, table))
+	if idx.using != "" {
+		buf.WriteString("USING " + idx.using + " ")
+	}
+	buf.WriteString("(" + strings.Join(elems, ", ") + ")")
+	if idx.where != "" {
+		buf.WriteString(" WHERE " + idx.where)
+	}
+	buf.WriteString(";\n")
+	return buf.String()
+}
+
+// splitGormTag split gorm tag by sep, escaped sep is kept, same as schema.ParseTagSetting of gorm
+func splitGormTag(tag, sep string) []string {
+	var list []string
+	for _, v := range strings.Split(tag, sep) {
+		if n := len(list); n > 0 && strings.HasSuffix(list[n-1], `\`) {
+			list[n-1] = strings.TrimSuffix(list[n-1], `\`) + sep + v
+			continue
+		}
+		list = append(list, v)
+	}
+	return list
+}
+
+// reverseComment table comment of struct doc, eg. // UserObj 用户, the default doc of generated struct is ignored
+func reverseComment(name string, doc *ast.CommentGroup) string {
+	if doc == nil {
+		return ""
+	}
+	text := strings.Join(strings.Fields(doc.Text()), " ")
+	text = strings.TrimPrefix(strings.TrimPrefix(text, name), " ")
+	if text == "data model" {
+		return ""
+	}
+	return text
+}
+
+// sqlQuote quote s as postgres string literal
+func sqlQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+func reverseTable(table, comment string, st *ast.StructType) ([]byte, error) {
+	var (
+		columns []*reverseColumn
+		indexes []*reverseIndex
+		primary []string
+	)
+	indexByName := make(map[string]*reverseIndex)
+	for _, f := range st.Fields.List {
+		typ := types.ExprString(f.Type)
+		// embedded gorm.Model
+		if len(f.Names) == 0 {
+			if typ != "gorm.Model" {
+				continue
+			}
+			columns = append(columns,
+				&reverseColumn{name: "id", sqlType: "SERIAL", notNull: true},
+				&reverseColumn{name: "created_at", sqlType: "TIMESTAMP"},
+				&reverseColumn{name: "updated_at", sqlType: "TIMESTAMP"},
+				&reverseColumn{name: "deleted_at", sqlType: "TIMESTAMP"},
+			)
+			primary = append(primary, "id")
+			continue
+		}
+		if !f.Names[0].IsExported() {
+			continue
+		}
+		var tag string
+		if f.Tag != nil {
+			tag, _ = strconv.Unquote(f.Tag.Value)
+			tag = reflect.StructTag(tag).Get("gorm")
+		}
+		if tag == "-" {
+			continue
+		}
+
+		col := &reverseColumn{name: strcase.ToSnake(f.Names[0].Name)}
+		typ = strings.TrimPrefix(typ, "*")
+		var (
+			isPrimary, autoIncrement bool
+			sqlType                  string
+		)
+		for _, v := range splitGormTag(tag, ";") {
+			key, val := v, ""
+			if i := strings.Index(v, ":"); i >= 0 {
+				key, val = v[:i], v[i+1:]
+			}
+			switch strings.ToLower(strings.TrimSpace(key)) {
+			case "column":
+				col.name = val
+			case "type":
+				sqlType = strings.ToUpper(val)
+			case "default":
+				col.defaultVal = val
+			case "not null":
+				col.notNull = true
+			case "comment":
+				col.comment = val
+			case "primarykey":
+				isPrimary = true
+			case "autoincrement":
+				autoIncrement = true
+			case "index", "uniqueindex":
+				options := splitGormTag(val, ",")
+				name := options[0]
+				if name == "" {
+					name = "idx_" + table + "_" + col.name
+				}
+				idx, ok := indexByName[name]
+				if !ok {
+					idx = &reverseIndex{name: name}
+					indexByName[name] = idx
+					indexes = append(indexes, idx)
+				}
+				idx.unique = idx.unique || strings.EqualFold(strings.TrimSpace(key), "uniqueIndex")
+				elem := &reverseIndexElem{column: col.name}
+				err := parseIndexTag(idx, elem, options[1:])
+				if err != nil {
+					return nil, err
+				}
+				idx.elems = append(idx.elems, elem)
+			}
+		}
+		if sqlType == "" {
+			sqlType = goTypeToSQL[typ]
+			if sqlType == "" {
+				return nil, errors.New("unsupported go type to pg: " + typ)
+			}
+		}
+		if isPrimary {
+			if autoIncrement {
+				sqlType = "SERIAL"
+			}
+			primary = append(primary, col.name)
+		}
+		col.sqlType = sqlType
+		columns = append(columns, col)
+	}
+
+	if len(columns) == 0 {
+		return nil, errors.New("no column found")
+	}
+
+	buf := new(bytes.Buffer)
+	buf.WriteString(fmt.Sprintf("CREATE TABLE IF NOT EXISTS \"%s\" (\n", table))
+	for _, v := range columns {
+		buf.WriteString(fmt.Sprintf("    %s %s", v.name, v.sqlType))
+		if v.defaultVal != "" {
+			buf.WriteString(" DEFAULT " + v.defaultVal)
+		}
+		if v.notNull {
+			buf.WriteString(" NOT NULL")
+		}
+		buf.WriteString(",\n")
+	}
+	if len(primary) > 0 {
+		buf.WriteString(fmt.Sprintf("    PRIMARY KEY (%s)\n", strings.Join(primary, ", ")))
+	} else {
+		// 去掉最后一列的逗号
+		buf.Truncate(buf.Len() - 2)
+		buf.WriteString("\n")
+	}
+	buf.WriteString(");\n")
+
+	if len(indexes) > 0 {
+		buf.WriteString("\n")
+	}
+	for _, v := range indexes {
+		buf.WriteString(v.indexSQL(table))
+	}
+
+	comments := comment != ""
+	if comments {
+		buf.WriteString(fmt.Sprintf("\nCOMMENT ON TABLE \"%s\" IS %s;\n", table, sqlQuote(comment)))
+	}
+	for _, v := range columns {
+		if v.comment == "" {
+			continue
+		}
+		if !comments {
+			buf.WriteString("\n")
+			comments = true
+		}
+		buf.WriteString(fmt.Sprintf("COMMENT ON COLUMN \"%s\".%s IS %s;\n", table, v.name, sqlQuote(v.comment)))
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,570 @@
+// Package model provides ...
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/iancoleman/strcase"
+)
+
+// Dialect DDL dialect, also decides which DB the code generate for
+type Dialect string
+
+// supported dialects
+const (
+	Postgres Dialect = "postgres"
+	MongoDB  Dialect = "mongodb"
+)
+
+// Schema parsed from DDL
+type Schema struct {
+	Dialect Dialect
+	Tables  []*Table
+}
+
+// Table parsed from CREATE TABLE and its COMMENT/INDEX statements
+type Table struct {
+	Name        string // 表名, eg. fido_credential
+	GoName      string // eg. FidoCredential
+	Comment     string // COMMENT ON TABLE
+	Columns     []*Column
+	PrimaryKey  *Column
+	Indexes     []*Index
+	ForeignKeys []*ForeignKey
+
+	View         bool // CREATE VIEW, 只读
+	Materialized bool // CREATE MATERIALIZED VIEW
+
+	File string // 生成的文件名(不含后缀), eg. fido_credential
+	Dir  string // custom文件相对dst的目录, 为空时位于dst
+	Hash string // 源DDL的sha256, 记录在生成文件头, 未变化时不重写
+
+	params *commandParams // Parse的结果, Generate时只读
+}
+
+// Column parsed table column
+type Column struct {
+	Name    string // 列名, eg. tenant_id
+	GoName  string // eg. TenantId
+	SQLType string // eg. VARCHAR(1024)
+	GoType  string // eg. string
+	Tag     string // gorm tag
+	NotNull bool
+	Default string
+	Comment string   // COMMENT ON COLUMN
+	Enum    []string // CREATE TYPE ... AS ENUM 或 CHECK (col IN (...))
+}
+
+// Index parsed table index
+type Index struct {
+	Name        string
+	Columns     []string // 列名, 表达式索引为表达式引用的列
+	Expressions []string // 与Columns对应, 表达式索引的表达式, 非表达式索引为空
+	Unique      bool
+	Using       string   // 索引类型, eg. gin
+	Include     []string // INCLUDE的列名
+	Where       string   // 部分索引条件
+}
+
+// ForeignKey parsed REFERENCES or FOREIGN KEY constraint
+type ForeignKey struct {
+	Columns    []string // 列名
+	RefTable   string   // 引用表名, eg. user
+	RefColumns []string // 引用列名, 未指定时为空, 即引用表的主键
+}
+
+// validation action of mongodb $jsonSchema validator
+const (
+	ValidatorStrict = "strict" // 拒绝不符合的写入
+	ValidatorWarn   = "warn"   // 仅记录日志
+)
+
+// Options for Generate
+type Options struct {
+	PkgName    string // dst的包名, 默认model
+	ImportPath string // dst的import path, eg. github.com/go-goll/ta-auth/model
+
+	Handlers   bool // 生成gin handler: handler/*.go
+	OpenAPI    bool // 生成openapi 3文档: openapi.json
+	TypeScript bool // 生成typescript类型及client: ts/*.ts
+	Proto      bool // 生成proto3及Obj转换: proto/*
+	Docs       bool // 生成表结构文档及mermaid ER图: docs/schema.md
+	Fake       bool // 生成内存fake repository用于测试: fake/*.go
+	Fixture    bool // 生成测试对象工厂及seed加载: fixture/*.go
+	Cache      bool // 生成Select的read-through缓存装饰器: cache/*.go
+
+	Validator string // mongodb $jsonSchema校验, ValidatorStrict/ValidatorWarn, 为空不校验
+	Tenant    string // 租户列名, eg. tenant_id, 含该列的表的DAO按context中的租户隔离
+
+	Audit   AuditColumns
+	History bool // 生成<table>_history记录Update/Delete前后的差异, 由List<Table>History查询
+
+	Jobs int // 并发生成表的数量, 默认1
+}
+
+// AuditColumns column names of audit trail, by columns are filled with actor of db.WithActor
+type AuditColumns struct {
+	CreatedAt string // 创建时间列, 默认created_at
+	UpdatedAt string // 更新时间列, 默认updated_at
+	CreatedBy string // 创建人列, eg. created_by, 为空不填充
+	UpdatedBy string // 更新人列, eg. updated_by, 为空不填充
+}
+
+// Parse parse DDL of one table
+func Parse(r io.Reader, dialect Dialect) (*Schema, error) {
+	if dialect != Postgres && dialect != MongoDB {
+		return nil, errors.New("unsupported dialect: " + string(dialect))
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	params, err := ddlAnalyzer(data)
+	if err != nil {
+		return nil, err
+	}
+	if params.TableName == "" {
+		return nil, errors.New("no CREATE TABLE statement found")
+	}
+	if dialect == MongoDB {
+		if params.View {
+			return nil, errors.New("views are not supported by mongodb: " + params.TableName)
+		}
+		mongoFields(params)
+	}
+	table := newTable(params)
+	sum := sha256.Sum256(data)
+	table.Hash = hex.EncodeToString(sum[:])
+	return &Schema{Dialect: dialect, Tables: []*Table{table}}, nil
+}
+
+func newTable(params *commandParams) *Table {
+	t := &Table{
+		Name:    strcase.ToSnake(params.TableName),
+		GoName:  params.TableName,
+		Comment: params.Comment,
+		File:    strcase.ToSnake(params.TableName),
+
+		View:         params.View,
+		Materialized: params.Materialized,
+		params:       params,
+	}
+	columns := make(map[*field]*Column)
+	for _, f := range params.Fields {
+		c := &Column{
+			Name:    f.column,
+			GoName:  f.Name,
+			SQLType: f.sqlType,
+			GoType:  f.Type,
+			Tag:     f.Tag,
+			NotNull: f.notNull,
+			Default: f.defaultVal,
+			Comment: f.Comment,
+			Enum:    f.enum,
+		}
+		columns[f] = c
+		t.Columns = append(t.Columns, c)
+	}
+	if params.Primary != nil {
+		t.PrimaryKey = columns[params.Primary.field]
+	}
+	added := make(map[string]bool)
+	for _, f := range params.Fields {
+		for _, idx := range f.indexs {
+			if added[idx.indexName] {
+				continue
+			}
+			added[idx.indexName] = true
+			v := &Index{
+				Name:    idx.indexName,
+				Unique:  idx.uniqueIndex,
+				Using:   idx.using,
+				Include: idx.include,
+				Where:   idx.where,
+			}
+			for i, vv := range idx.indexFields {
+				v.Columns = append(v.Columns, columns[vv].Name)
+				if idx.exprs != nil && idx.exprs[i] != "" {
+					v.Expressions = make([]string, len(idx.exprs))
+					copy(v.Expressions, idx.exprs)
+				}
+			}
+			t.Indexes = append(t.Indexes, v)
+		}
+	}
+	for _, fk := range params.foreignKeys {
+		v := &ForeignKey{RefTable: fk.refTable, RefColumns: fk.refColumns}
+		for _, f := range fk.fields {
+			v.Columns = append(v.Columns, columns[f].Name)
+		}
+		t.ForeignKeys = append(t.ForeignKeys, v)
+	}
+	return t
+}
+
+// tableParams params of t for one Generate, the exported fields of t are the source,
+// options which are not exported, eg. sort of index and mongodb index options, are kept from Parse
+func tableParams(t *Table, dialect Dialect) (*commandParams, error) {
+	parsed := t.params
+	if parsed == nil {
+		parsed = &commandParams{}
+	}
+	params := &commandParams{
+		TableName:    t.GoName,
+		Comment:      t.Comment,
+		View:         t.View,
+		Materialized: t.Materialized,
+		enums:        parsed.enums,
+		issues:       parsed.issues,
+	}
+
+	// 字段, 保留解析时的bson等
+	oldFields := make(map[string]*field)
+	for _, f := range parsed.Fields {
+		oldFields[f.column] = f
+	}
+	fields := make(map[string]*field)
+	for _, c := range t.Columns {
+		f := &field{}
+		if old, ok := oldFields[c.Name]; ok {
+			*f = *old
+			f.indexs = nil
+		}
+		f.column, f.sqlType, f.enum, f.defaultVal, f.notNull = c.Name, c.SQLType, c.Enum, c.Default, c.NotNull
+		f.Name, f.Type, f.Tag, f.Comment = c.GoName, c.GoType, c.Tag, c.Comment
+		f.createdAt, f.updatedAt = strings.Contains(f.Tag, ";autoCreateTime"), strings.Contains(f.Tag, ";autoUpdateTime")
+		if _, ok := fields[c.Name]; ok {
+			return nil, fmt.Errorf("%s: duplicate column %s", t.Name, c.Name)
+		}
+		fields[c.Name] = f
+		params.Fields = append(params.Fields, f)
+	}
+	column := func(name string) (*field, error) {
+		f, ok := fields[name]
+		if !ok {
+			return nil, fmt.Errorf("%s: unknown column %s", t.Name, name)
+		}
+		return f, nil
+	}
+
+	if t.PrimaryKey != nil {
+		f, err := column(t.PrimaryKey.Name)
+		if err != nil {
+			return nil, err
+		}
+		params.Primary = &primaryKey{field: f}
+		if parsed.Primary != nil && parsed.Primary.column == f.column {
+			params.Primary.Autoincrement, params.Primary.ShortID = parsed.Primary.Autoincrement, parsed.Primary.ShortID
+		} else {
+			params.Primary.Autoincrement = strings.HasSuffix(strings.ToUpper(f.sqlType), "SERIAL") || f.Type == "primitive.ObjectID"
+		}
+	}
+	if dialect == MongoDB {
+		for _, f := range params.Fields {
+			if _, ok := oldFields[f.column]; ok {
+				continue
+			}
+			f.Bson = f.column // 与mongoFields一致
+			if params.Primary != nil && f == params.Primary.field {
+				f.Bson = "_id"
+			}
+			if !f.notNull || (params.Primary != nil && f == params.Primary.field && params.Primary.Autoincrement) {
+				f.Bson += ",omitempty"
+			}
+		}
+	}
+
+	// 索引, 每个字段的索引保持解析时的顺序
+	oldIndexes := make(map[string]index)
+	for _, f := range parsed.Fields {
+		for _, idx := range f.indexs {
+			oldIndexes[idx.indexName] = idx
+		}
+	}
+	for _, v := range t.Indexes {
+		old := oldIndexes[v.Name]
+		idx := index{
+			uniqueIndex:        v.Unique,
+			normalIndex:        !v.Unique,
+			indexName:          v.Name,
+			using:              v.Using,
+			include:            v.Include,
+			where:              v.Where,
+			multiColumnExpr:    old.multiColumnExpr,
+			concurrently:       old.concurrently,
+			sparse:             old.sparse,
+			text:               old.text,
+			ttl:                old.ttl,
+			expireAfterSeconds: old.expireAfterSeconds,
+		}
+		for i, name := range v.Columns {
+			f, err := column(name)
+			if err != nil {
+				return nil, fmt.Errorf("index %s: %w", v.Name, err)
+			}
+			idx.indexFields = append(idx.indexFields, f)
+			expr, sort := "", ""
+			if i < len(v.Expressions) {
+				expr = v.Expressions[i]
+			}
+			if i < len(old.sorts) && i < len(old.indexFields) && old.indexFields[i].column == name {
+				sort = old.sorts[i]
+			}
+			idx.exprs = append(idx.exprs, expr)
+			idx.sorts = append(idx.sorts, sort)
+		}
+		for _, f := range idx.indexFields {
+			f.indexs = append(f.indexs, idx)
+		}
+	}
+	for _, f := range params.Fields {
+		old, ok := oldFields[f.column]
+		if !ok {
+			continue
+		}
+		rank := make(map[string]int)
+		for i, idx := range old.indexs {
+			rank[idx.indexName] = i + 1
+		}
+		sort.SliceStable(f.indexs, func(i, j int) bool {
+			ri, rj := rank[f.indexs[i].indexName], rank[f.indexs[j].indexName]
+			return ri != 0 && (rj == 0 || ri < rj)
+		})
+	}
+
+	for _, v := range t.ForeignKeys {
+		fk := foreignKey{refTable: v.RefTable, refColumns: v.RefColumns}
+		for _, name := range v.Columns {
+			f, err := column(name)
+			if err != nil {
+				return nil, fmt.Errorf("foreign key: %w", err)
+			}
+			fk.fields = append(fk.fields, f)
+		}
+		params.foreignKeys = append(params.foreignKeys, fk)
+	}
+	return params, nil
+}
+
+// Generate generate code of schema, returns file content by path relative to dst.
+// Code is generated from the exported fields, so the schema can be built or adjusted by hand,
+// options which are not exported are kept from Parse. schema is not modified by Generate,
+// it can be generated again with other options.
+func Generate(schema *Schema, opts Options) (map[string][]byte, error) {
+	if opts.PkgName == "" {
+		opts.PkgName = "model"
+	}
+	generator, err := newFileGenerator(schema.Dialect, opts)
+	if err != nil {
+		return nil, err
+	}
+	// 生成时修改params, 使用副本
+	tables := make([]*Table, len(schema.Tables))
+	for i, t := range schema.Tables {
+		params, err := tableParams(t, schema.Dialect)
+		if err != nil {
+			return nil, err
+		}
+		copied := *t
+		copied.params = params
+		tables[i] = &copied
+	}
+	schema = &Schema{Dialect: schema.Dialect, Tables: tables}
+
+	internalTmpl, modelTmpl := dialectTemplates(schema.Dialect)
+	files := make(map[string][]byte)
+	list := make([]*commandParams, len(schema.Tables))
+	internals := make([][]byte, len(schema.Tables))
+	customs := make([][]byte, len(schema.Tables))
+	err = runJobs(len(schema.Tables), opts.Jobs, func(i int) error {
+		t := schema.Tables[i]
+		params := t.params
+		err := scopeTenant(params, opts.Tenant)
+		if err != nil {
+			return err
+		}
+		err = auditTable(params, opts)
+		if err != nil {
+			return err
+		}
+		queryTable(params, schema.Dialect)
+		// internal file
+		data, err := generator.generateInternalFile(params)
+		if err != nil {
+			return fmt.Errorf("%s: %w", t.Name, err)
+		}
+		internals[i] = stampHash(data, sourceHash(opts, internalTmpl, t))
+
+		// custom file
+		params.PkgName = opts.PkgName
+		if t.Dir != "" {
+			params.PkgName = filepath.Base(t.Dir)
+		}
+		params.Import = path.Join(opts.ImportPath, "internal")
+		customs[i], err = generator.generateCustomFile(params)
+		if err != nil {
+			return fmt.Errorf("%s: %w", t.Name, err)
+		}
+
+		// model file
+		params.Import = ""
+		if t.Dir != "" {
+			params.Import = path.Join(opts.ImportPath, filepath.ToSlash(t.Dir))
+		}
+		list[i] = params
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i, t := range schema.Tables {
+		files[filepath.Join("internal", t.File+".go")] = internals[i]
+		files[t.customFile()] = customs[i]
+	}
+	data, err := generator.generateModelFile(list)
+	if err != nil {
+		return nil, err
+	}
+	files["model.go"] = stampHash(data, sourceHash(opts, modelTmpl, schema.Tables...))
+
+	if opts.Handlers {
+		err = generateHandlers(schema, opts, files)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if opts.OpenAPI {
+		files["openapi.json"], err = generateOpenAPI(schema, opts)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if opts.TypeScript {
+		err = generateTypeScript(schema, files)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if opts.Proto {
+		err = generateProto(schema, opts, files)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if opts.Fake {
+		err = generateFakes(schema, opts, files)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if opts.Fixture {
+		err = generateFixtures(schema, opts, files)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if opts.Cache {
+		err = generateCaches(schema, opts, files)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if opts.Docs {
+		docs, err := GenerateDocs(schema, opts.PkgName, DocsMermaid)
+		if err != nil {
+			return nil, err
+		}
+		for name, data := range docs {
+			files[filepath.Join("docs", name)] = data
+		}
+	}
+	return files, nil
+}
+
+func generateHandlers(schema *Schema, opts Options, files map[string][]byte) error {
+	generator, err := newHandlerGenerator(schema.Dialect)
+	if err != nil {
+		return err
+	}
+	tables := schema.writable()
+	list := make([]*commandParams, len(tables))
+	for i, t := range tables {
+		data, err := generator.generateHandlerFile(opts.ImportPath, t.params)
+		if err != nil {
+			return err
+		}
+		files[filepath.Join("handler", t.File+".go")] = data
+		list[i] = t.params
+	}
+	data, err := generator.generateRouterFile(opts.ImportPath, list)
+	if err != nil {
+		return err
+	}
+	files[filepath.Join("handler", "router.go")] = data
+	return nil
+}
+
+// tenantZero zero literal of supported go types of tenant column
+var tenantZero = map[string]string{
+	"int":                "0",
+	"int32":              "0",
+	"int64":              "0",
+	"string":             `""`,
+	"primitive.ObjectID": "primitive.NilObjectID",
+}
+
+// scopeTenant set tenant field of params if the table has the tenant column
+func scopeTenant(params *commandParams, column string) error {
+	params.Tenant, params.TenantZero = nil, ""
+	if column == "" {
+		return nil
+	}
+	for _, f := range params.Fields {
+		if f.column != column {
+			continue
+		}
+		if params.Primary != nil && f == params.Primary.field {
+			return fmt.Errorf("tenant column %s is primary key of %s", column, params.TableName)
+		}
+		zero, ok := tenantZero[f.Type]
+		if !ok {
+			return fmt.Errorf("unsupported type %s of tenant column %s", f.Type, column)
+		}
+		params.Tenant, params.TenantZero = f, zero
+	}
+	return nil
+}
+
+// writable tables except views, handlers, api documents and fixtures are generated for them only
+func (s *Schema) writable() []*Table {
+	var list []*Table
+	for _, t := range s.Tables {
+		if !t.View {
+			list = append(list, t)
+		}
+	}
+	return list
+}
+
+// customFile 用户可修改的文件, 已存在时不覆盖
+func (t *Table) customFile() string {
+	return filepath.Join(t.Dir, t.File+".go")
+}
+
+func newFileGenerator(dialect Dialect, opts Options) (fileGenerator, error) {
+	switch dialect {
+	case Postgres:
+		return newPostgresGenerator()
+	case MongoDB:
+		return newMongoDBGenerator(opts.Validator)
+	}
+	return nil, errors.New("unsupported dialect: " + string(dialect))
+}
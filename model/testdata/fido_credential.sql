@@ -0,0 +1,26 @@
+CREATE TABLE IF NOT EXISTS "fido_credential" (
+    id SERIAL NOT NULL,
+    tenant_id INTEGER NOT NULL,
+
+    credential_id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    public_key bytea NOT NULL,
+    authenticator_id INTEGER NOT NULL,
+    sign_count INTEGER NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX idx_fido_credential_credential_id ON fido_credential (credential_id);
+CREATE INDEX idx_fido_credential_user_id ON fido_credential (user_id);
+
+COMMENT ON TABLE fido_credential IS '凭证表';
+COMMENT ON COLUMN fido_credential.id IS '自增ID';
+COMMENT ON COLUMN fido_credential.tenant_id IS '租户ID';
+COMMENT ON COLUMN fido_credential.credential_id IS '凭证ID';
+COMMENT ON COLUMN fido_credential.user_id IS '用户ID';
+COMMENT ON COLUMN fido_credential.public_key IS '公钥';
+COMMENT ON COLUMN fido_credential.authenticator_id IS '认证器ID';
+COMMENT ON COLUMN fido_credential.sign_count IS '签名次数';
+COMMENT ON COLUMN fido_credential.updated_at IS '更新时间';
+COMMENT ON COLUMN fido_credential.created_at IS '创建时间';
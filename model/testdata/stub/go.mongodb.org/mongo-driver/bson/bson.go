@@ -0,0 +1,14 @@
+// Package bson stub of go.mongodb.org/mongo-driver/bson for type checking of generated code
+package bson
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+type (
+	E = primitive.E
+	D = primitive.D
+	M = primitive.M
+	A = primitive.A
+)
+
+func Marshal(v interface{}) ([]byte, error)   { return nil, nil }
+func Unmarshal(b []byte, v interface{}) error { return nil }
@@ -0,0 +1,19 @@
+// Package primitive stub of go.mongodb.org/mongo-driver/bson/primitive for type checking of generated code
+package primitive
+
+type ObjectID [12]byte
+
+var NilObjectID ObjectID
+
+func NewObjectID() ObjectID                      { return ObjectID{1} }
+func ObjectIDFromHex(s string) (ObjectID, error) { return ObjectID{}, nil }
+func (id ObjectID) Hex() string                  { return "" }
+func (id ObjectID) IsZero() bool                 { return id == NilObjectID }
+
+type E struct {
+	Key   string
+	Value interface{}
+}
+type D []E
+type M map[string]interface{}
+type A []interface{}
@@ -0,0 +1,90 @@
+// Package mongo stub of go.mongodb.org/mongo-driver/mongo for type checking of generated code
+package mongo
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ErrNoDocuments = errors.New("mongo: no documents in result")
+
+type WriteError struct {
+	Index   int
+	Code    int
+	Message string
+}
+type WriteErrors []WriteError
+type WriteException struct {
+	WriteErrors WriteErrors
+}
+
+func (e WriteException) Error() string { return "" }
+
+func IsDuplicateKeyError(err error) bool { return false }
+
+type Database struct{}
+
+func (db *Database) Collection(name string) *Collection { return &Collection{} }
+func (db *Database) ListCollectionNames(ctx context.Context, filter interface{}) ([]string, error) {
+	return nil, nil
+}
+func (db *Database) CreateCollection(ctx context.Context, name string, opts ...*options.CreateCollectionOptions) error {
+	return nil
+}
+func (db *Database) RunCommand(ctx context.Context, cmd interface{}) *SingleResult {
+	return &SingleResult{}
+}
+
+type IndexModel struct {
+	Keys    interface{}
+	Options *options.IndexOptions
+}
+type IndexView struct{}
+
+func (v IndexView) CreateOne(ctx context.Context, model IndexModel) (string, error) { return "", nil }
+
+func (v IndexView) CreateMany(ctx context.Context, models []IndexModel) ([]string, error) {
+	return nil, nil
+}
+
+type InsertOneResult struct{ InsertedID interface{} }
+type DeleteResult struct{ DeletedCount int64 }
+type UpdateResult struct{ MatchedCount, ModifiedCount int64 }
+type SingleResult struct{}
+
+func (r *SingleResult) Decode(v interface{}) error { return nil }
+func (r *SingleResult) Err() error                 { return nil }
+
+type Cursor struct{}
+
+func (c *Cursor) All(ctx context.Context, results interface{}) error { return nil }
+
+type Collection struct{}
+
+func (c *Collection) Indexes() IndexView { return IndexView{} }
+func (c *Collection) InsertOne(ctx context.Context, doc interface{}) (*InsertOneResult, error) {
+	return nil, nil
+}
+func (c *Collection) DeleteOne(ctx context.Context, filter interface{}) (*DeleteResult, error) {
+	return nil, nil
+}
+func (c *Collection) DeleteMany(ctx context.Context, filter interface{}) (*DeleteResult, error) {
+	return nil, nil
+}
+func (c *Collection) UpdateOne(ctx context.Context, filter, update interface{}, opts ...*options.UpdateOptions) (*UpdateResult, error) {
+	return nil, nil
+}
+func (c *Collection) UpdateMany(ctx context.Context, filter, update interface{}, opts ...*options.UpdateOptions) (*UpdateResult, error) {
+	return nil, nil
+}
+func (c *Collection) FindOne(ctx context.Context, filter interface{}, opts ...*options.FindOneOptions) *SingleResult {
+	return &SingleResult{}
+}
+func (c *Collection) Find(ctx context.Context, filter interface{}, opts ...*options.FindOptions) (*Cursor, error) {
+	return nil, nil
+}
+func (c *Collection) CountDocuments(ctx context.Context, filter interface{}, opts ...*options.CountOptions) (int64, error) {
+	return 0, nil
+}
@@ -0,0 +1,45 @@
+// Package options stub of go.mongodb.org/mongo-driver/mongo/options for type checking of generated code
+package options
+
+type IndexOptions struct{}
+
+func Index() *IndexOptions                                                     { return &IndexOptions{} }
+func (o *IndexOptions) SetName(s string) *IndexOptions                         { return o }
+func (o *IndexOptions) SetUnique(b bool) *IndexOptions                         { return o }
+func (o *IndexOptions) SetSparse(b bool) *IndexOptions                         { return o }
+func (o *IndexOptions) SetExpireAfterSeconds(n int32) *IndexOptions            { return o }
+func (o *IndexOptions) SetPartialFilterExpression(m interface{}) *IndexOptions { return o }
+
+type FindOptions struct{}
+
+func Find() *FindOptions                                  { return &FindOptions{} }
+func (o *FindOptions) SetSkip(n int64) *FindOptions       { return o }
+func (o *FindOptions) SetLimit(n int64) *FindOptions      { return o }
+func (o *FindOptions) SetSort(s interface{}) *FindOptions { return o }
+
+type FindOneOptions struct{}
+
+func FindOne() *FindOneOptions { return &FindOneOptions{} }
+
+type CountOptions struct{}
+
+func Count() *CountOptions                             { return &CountOptions{} }
+func (o *CountOptions) SetLimit(n int64) *CountOptions { return o }
+
+type CreateCollectionOptions struct{}
+
+func CreateCollection() *CreateCollectionOptions { return &CreateCollectionOptions{} }
+func (o *CreateCollectionOptions) SetValidator(v interface{}) *CreateCollectionOptions {
+	return o
+}
+func (o *CreateCollectionOptions) SetValidationLevel(s string) *CreateCollectionOptions {
+	return o
+}
+func (o *CreateCollectionOptions) SetValidationAction(s string) *CreateCollectionOptions {
+	return o
+}
+
+type UpdateOptions struct{}
+
+func Update() *UpdateOptions                             { return &UpdateOptions{} }
+func (o *UpdateOptions) SetUpsert(b bool) *UpdateOptions { return o }
@@ -0,0 +1,8 @@
+// Package fieldmaskpb stub of google.golang.org/protobuf/types/known/fieldmaskpb for type checking of generated code
+package fieldmaskpb
+
+type FieldMask struct {
+	Paths []string
+}
+
+func (x *FieldMask) GetPaths() []string { return x.Paths }
@@ -0,0 +1,7 @@
+// Package structpb stub of google.golang.org/protobuf/types/known/structpb for type checking of generated code
+package structpb
+
+type Value struct{}
+
+func (x *Value) MarshalJSON() ([]byte, error) { return nil, nil }
+func (x *Value) UnmarshalJSON(b []byte) error { return nil }
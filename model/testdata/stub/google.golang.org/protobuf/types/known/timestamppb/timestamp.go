@@ -0,0 +1,12 @@
+// Package timestamppb stub of google.golang.org/protobuf/types/known/timestamppb for type checking of generated code
+package timestamppb
+
+import "time"
+
+type Timestamp struct {
+	Seconds int64
+	Nanos   int32
+}
+
+func New(t time.Time) *Timestamp       { return &Timestamp{} }
+func (x *Timestamp) AsTime() time.Time { return time.Time{} }
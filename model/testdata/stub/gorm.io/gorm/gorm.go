@@ -0,0 +1,70 @@
+// Package gorm stub of gorm.io/gorm for type checking of generated code
+package gorm
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+var (
+	ErrRecordNotFound = errors.New("record not found")
+	ErrDuplicatedKey  = errors.New("duplicated key not allowed")
+)
+
+type DeletedAt sql.NullTime
+
+type Expr struct{}
+
+type DB struct {
+	Error        error
+	RowsAffected int64
+	Statement    *Statement
+}
+
+type Statement struct {
+	Table string
+	Model interface{}
+}
+
+type Session struct{}
+
+func (db *DB) Where(query interface{}, args ...interface{}) *DB { return db }
+func (db *DB) Or(query interface{}, args ...interface{}) *DB    { return db }
+func (db *DB) Not(query interface{}, args ...interface{}) *DB   { return db }
+func (db *DB) Model(value interface{}) *DB                      { return db }
+func (db *DB) Table(name string, args ...interface{}) *DB       { return db }
+func (db *DB) Delete(value interface{}, conds ...interface{}) *DB {
+	return db
+}
+func (db *DB) Updates(values interface{}) *DB                    { return db }
+func (db *DB) Update(column string, value interface{}) *DB       { return db }
+func (db *DB) UpdateColumns(values interface{}) *DB              { return db }
+func (db *DB) First(dest interface{}, conds ...interface{}) *DB  { return db }
+func (db *DB) Take(dest interface{}, conds ...interface{}) *DB   { return db }
+func (db *DB) Find(dest interface{}, conds ...interface{}) *DB   { return db }
+func (db *DB) Count(count *int64) *DB                            { return db }
+func (db *DB) Offset(offset int) *DB                             { return db }
+func (db *DB) Limit(limit int) *DB                               { return db }
+func (db *DB) Order(value interface{}) *DB                       { return db }
+func (db *DB) Create(value interface{}) *DB                      { return db }
+func (db *DB) Save(value interface{}) *DB                        { return db }
+func (db *DB) Raw(sql string, values ...interface{}) *DB         { return db }
+func (db *DB) Exec(sql string, values ...interface{}) *DB        { return db }
+func (db *DB) Select(query interface{}, args ...interface{}) *DB { return db }
+func (db *DB) Omit(columns ...string) *DB                        { return db }
+func (db *DB) Scan(dest interface{}) *DB                         { return db }
+func (db *DB) Pluck(column string, dest interface{}) *DB         { return db }
+func (db *DB) Unscoped() *DB                                     { return db }
+func (db *DB) Session(config *Session) *DB                       { return db }
+func (db *DB) Scopes(funcs ...func(*DB) *DB) *DB                 { return db }
+func (db *DB) Clauses(conds ...interface{}) *DB                  { return db }
+func (db *DB) WithContext(ctx interface{}) *DB                   { return db }
+func (db *DB) AddError(err error) error                          { return err }
+func (db *DB) AutoMigrate(dst ...interface{}) error              { return nil }
+func (db *DB) Transaction(fc func(tx *DB) error, opts ...*sql.TxOptions) error {
+	return fc(db)
+}
+func (db *DB) Callback() interface{} { return nil }
+
+var _ = time.Now
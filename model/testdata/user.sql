@@ -0,0 +1,9 @@
+CREATE TABLE "user" (
+    id         SERIAL                              NOT NULL,
+    name       TEXT      DEFAULT 1                 NOT NULL,
+    age        INTEGER                             NOT NULL,
+    email      TEXT                                NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX idx_user_email_age ON "user" (email, age);
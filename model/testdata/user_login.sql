@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS "user_login" (
+    id                 SERIAL    NOT NULL,
+    user_id            INTEGER   NOT NULL REFERENCES "user" (id) ON DELETE CASCADE,
+    fido_credential_id INTEGER,
+    ip                 TEXT      NOT NULL,
+    created_at         TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_user_login_fido_credential FOREIGN KEY (fido_credential_id) REFERENCES fido_credential (id),
+    PRIMARY KEY (id)
+);
+
+COMMENT ON TABLE user_login IS '登录记录';
+COMMENT ON COLUMN user_login.user_id IS '用户ID';
+COMMENT ON COLUMN user_login.fido_credential_id IS '登录使用的凭证';
+COMMENT ON COLUMN user_login.ip IS '登录IP';
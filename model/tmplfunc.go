@@ -4,6 +4,7 @@ package model
 import (
 	"io"
 	"strings"
+	"sync"
 	"text/template"
 
 	"github.com/iancoleman/strcase"
@@ -17,20 +18,42 @@ func init() {
 		"toLowerCamel": strcase.ToLowerCamel,
 		"toSnake":      strcase.ToSnake,
 		"stringsJoin":  strings.Join,
+		"mdEscape":     mdEscape,
+		"jsonString":   jsonString,
 	})
 }
 
-// templateIns with function
-var templateIns *template.Template
+// jsonString ",string" option of json tag of int64, number of javascript loses precision above 2^53
+func jsonString(goType string) string {
+	if goType == "int64" {
+		return ",string"
+	}
+	return ""
+}
+
+// mdEscape escape text in markdown table cell
+func mdEscape(s string) string {
+	return strings.NewReplacer("|", "\\|", "\r\n", " ", "\n", " ").Replace(s)
+}
+
+// templateIns with function, guarded by templateMu for parallel generation
+var (
+	templateIns *template.Template
+	templateMu  sync.RWMutex
+)
 
-// ParseTemplate parse text
+// ParseTemplate parse text, safe for concurrent use
 func ParseTemplate(name string, text string) error {
+	templateMu.Lock()
+	defer templateMu.Unlock()
 	t := templateIns.New(name)
 	_, err := t.Parse(text)
 	return err
 }
 
-// ExecuteTemplate execute template
+// ExecuteTemplate execute template, safe for concurrent use
 func ExecuteTemplate(wr io.Writer, name string, params interface{}) error {
+	templateMu.RLock()
+	defer templateMu.RUnlock()
 	return templateIns.ExecuteTemplate(wr, name, params)
 }
@@ -0,0 +1,156 @@
+// Package model provides ...
+package model
+
+import (
+	"bytes"
+	_ "embed" // embed
+	"encoding/json"
+	"path/filepath"
+	"strings"
+
+	"github.com/iancoleman/strcase"
+)
+
+//go:embed template/ts_model.tmpl
+var tsModelTmpl string
+
+//go:embed template/ts_client.tmpl
+var tsClientTmpl string
+
+type tsTable struct {
+	Name    string // eg. FidoCredential
+	Path    string // 路由, eg. fido_credential
+	Comment string
+	IDType  string
+
+	Enums  []*tsEnum
+	Fields []*tsField
+	Create []*tsField
+	Update []*tsField
+}
+
+type tsEnum struct {
+	Name  string // eg. UserStatus
+	Union string // eg. "active" | "disabled"
+}
+
+type tsField struct {
+	Name     string // json name
+	Type     string
+	Comment  string
+	Optional bool
+}
+
+// goTypeToTS go type of sqlTypeToGo to typescript type
+func goTypeToTS(goType string) string {
+	switch goType {
+	case "int", "int32":
+		return "number"
+	case "int64":
+		// json tag with ",string", see jsonString
+		return "string"
+	case "bool":
+		return "boolean"
+	case "string", "time.Time", "primitive.ObjectID":
+		return "string"
+	case "[]byte":
+		// base64
+		return "string | null"
+	case "gorm.DeletedAt":
+		return "string | null"
+	case "db.StringArray":
+		return "string[] | null"
+	case "db.Int64Array":
+		return "number[] | null"
+	}
+	// json.RawMessage etc.
+	return "unknown"
+}
+
+// tsComment text in /** */, "*/" ends the comment
+func tsComment(s string) string {
+	return strings.NewReplacer("*/", "*\\/", "\r\n", " ", "\n", " ").Replace(s)
+}
+
+// generateTypeScript generate typescript types and client of handler
+func generateTypeScript(schema *Schema, files map[string][]byte) error {
+	err := ParseTemplate("tsModelTmpl", tsModelTmpl)
+	if err != nil {
+		return err
+	}
+	err = ParseTemplate("tsClientTmpl", tsClientTmpl)
+	if err != nil {
+		return err
+	}
+
+	tables := schema.writable()
+	list := make([]*tsTable, len(tables))
+	for i, t := range tables {
+		params := t.params
+		tt := &tsTable{
+			Name:    params.TableName,
+			Path:    strcase.ToSnake(params.TableName),
+			Comment: tsComment(params.Comment),
+			IDType:  "string",
+		}
+		if params.Primary != nil {
+			tt.IDType = goTypeToTS(params.Primary.Type)
+		}
+		// enum type of field
+		fieldType := make(map[*field]string)
+		for _, f := range params.Fields {
+			fieldType[f] = goTypeToTS(f.Type)
+			if len(f.enum) == 0 {
+				continue
+			}
+			values := make([]string, len(f.enum))
+			for j, v := range f.enum {
+				data, _ := json.Marshal(v)
+				values[j] = string(data)
+			}
+			e := &tsEnum{Name: params.TableName + f.Name, Union: strings.Join(values, " | ")}
+			tt.Enums = append(tt.Enums, e)
+			fieldType[f] = e.Name
+		}
+
+		for _, f := range params.Fields {
+			tt.Fields = append(tt.Fields, &tsField{
+				Name:    strcase.ToSnake(f.Name),
+				Type:    fieldType[f],
+				Comment: tsComment(f.Comment),
+			})
+		}
+		create, update := handlerFields(params)
+		for _, v := range create {
+			tt.Create = append(tt.Create, &tsField{
+				Name:     v.JSON,
+				Type:     fieldType[v.src],
+				Comment:  tsComment(v.src.Comment),
+				Optional: !v.Required,
+			})
+		}
+		for _, v := range update {
+			tt.Update = append(tt.Update, &tsField{
+				Name:    v.JSON,
+				Type:    fieldType[v.src],
+				Comment: tsComment(v.src.Comment),
+			})
+		}
+		list[i] = tt
+	}
+
+	buf := new(bytes.Buffer)
+	err = ExecuteTemplate(buf, "tsModelTmpl", list)
+	if err != nil {
+		return err
+	}
+	files[filepath.Join("ts", "model.ts")] = append([]byte(nil), buf.Bytes()...)
+
+	buf.Reset()
+	err = ExecuteTemplate(buf, "tsClientTmpl", list)
+	if err != nil {
+		return err
+	}
+	files[filepath.Join("ts", "client.ts")] = append([]byte(nil), buf.Bytes()...)
+	return nil
+}